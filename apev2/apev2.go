@@ -0,0 +1,124 @@
+// Package apev2 читает теги APEv2 (и APEv1), которые хранятся в конце файлов
+// WavPack, Monkey's Audio, TAK и других форматов.
+package apev2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNoTag возвращается, если в конце файла нет тега APE.
+var ErrNoTag = errors.New("no APE tag")
+
+// footerSize — размер заголовка и футера тега APE.
+const footerSize = 32
+
+// id3v1Size — размер тега ID3v1, который может стоять после тега APE.
+const id3v1Size = 128
+
+// Тип значения элемента (биты 1-2 флагов).
+const (
+	ItemText     = 0 // Текст в UTF-8.
+	ItemBinary   = 1 // Двоичные данные.
+	ItemExternal = 2 // Ссылка на внешний ресурс.
+)
+
+// Item — элемент тега APE.
+type Item struct {
+	Key   string
+	Value []byte
+	Flags uint32
+}
+
+// Type возвращает тип значения элемента: ItemText, ItemBinary или ItemExternal.
+func (it Item) Type() int {
+	return int(it.Flags >> 1 & 0x03)
+}
+
+// Tag — тег APE. Ключи элементов сравниваются без учета регистра.
+type Tag struct {
+	Version int // 1000 для APEv1, 2000 для APEv2.
+	Items   []Item
+}
+
+// Get возвращает элемент с ключом key.
+func (t *Tag) Get(key string) (Item, bool) {
+	for _, it := range t.Items {
+		if strings.EqualFold(it.Key, key) {
+			return it, true
+		}
+	}
+	return Item{}, false
+}
+
+// Read ищет тег APE в конце данных r длиной size: сразу перед концом
+// или перед тегом ID3v1.
+func Read(r io.ReaderAt, size int64) (*Tag, error) {
+	for _, end := range []int64{size, size - id3v1Size} {
+		if end < footerSize {
+			continue
+		}
+		var footer [footerSize]byte
+		if _, err := r.ReadAt(footer[:], end-footerSize); err != nil {
+			return nil, err
+		}
+		if string(footer[:8]) == "APETAGEX" {
+			return parse(r, footer[:], end)
+		}
+	}
+	return nil, ErrNoTag
+}
+
+// ReadFile читает тег APE файла path.
+func ReadFile(path string) (*Tag, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Read(f, info.Size())
+}
+
+// parse разбирает элементы тега по футеру, заканчивающемуся в позиции end.
+func parse(r io.ReaderAt, footer []byte, end int64) (*Tag, error) {
+	version := binary.LittleEndian.Uint32(footer[8:])
+	size := int64(binary.LittleEndian.Uint32(footer[12:])) // Элементы и футер, без заголовка.
+	count := binary.LittleEndian.Uint32(footer[16:])
+	if size < footerSize || size > end {
+		return nil, fmt.Errorf("invalid APE tag size %d", size)
+	}
+
+	data := make([]byte, size-footerSize)
+	if _, err := r.ReadAt(data, end-size); err != nil {
+		return nil, err
+	}
+	tag := &Tag{Version: int(version)}
+	for range count {
+		if len(data) < 8 {
+			return nil, errors.New("truncated APE tag item")
+		}
+		valueSize := binary.LittleEndian.Uint32(data)
+		flags := binary.LittleEndian.Uint32(data[4:])
+		data = data[8:]
+		keyEnd := bytes.IndexByte(data, 0)
+		if keyEnd < 0 || uint64(valueSize) > uint64(len(data)-keyEnd-1) {
+			return nil, errors.New("truncated APE tag item")
+		}
+		tag.Items = append(tag.Items, Item{
+			Key:   string(data[:keyEnd]),
+			Value: data[keyEnd+1 : keyEnd+1+int(valueSize)],
+			Flags: flags,
+		})
+		data = data[keyEnd+1+int(valueSize):]
+	}
+	return tag, nil
+}
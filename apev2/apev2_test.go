@@ -0,0 +1,70 @@
+package apev2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildTag кодирует тег APEv2 с футером (без заголовка) из пар ключ-значение.
+func buildTag(items ...Item) []byte {
+	var body []byte
+	for _, it := range items {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(it.Value)))
+		body = binary.LittleEndian.AppendUint32(body, it.Flags)
+		body = append(body, it.Key...)
+		body = append(body, 0)
+		body = append(body, it.Value...)
+	}
+	footer := []byte("APETAGEX")
+	footer = binary.LittleEndian.AppendUint32(footer, 2000)
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(body)+footerSize))
+	footer = binary.LittleEndian.AppendUint32(footer, uint32(len(items)))
+	footer = binary.LittleEndian.AppendUint32(footer, 0)
+	footer = append(footer, make([]byte, 8)...)
+	return append(body, footer...)
+}
+
+// TestRead checks finding the tag at the end of a file and before ID3v1.
+func TestRead(t *testing.T) {
+	tag := buildTag(
+		Item{Key: "Artist", Value: []byte("Alice")},
+		Item{Key: "Cover Art (Front)", Value: []byte{0, 1, 2}, Flags: ItemBinary << 1},
+	)
+	audio := []byte("wvpk audio blocks")
+	id3v1 := append([]byte("TAG"), make([]byte, id3v1Size-3)...)
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{"at the end", append(append([]byte{}, audio...), tag...)},
+		{"before ID3v1", append(append(append([]byte{}, audio...), tag...), id3v1...)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Read(bytes.NewReader(tc.data), int64(len(tc.data)))
+			if err != nil {
+				t.Fatalf("Read() returned an unexpected error: %v", err)
+			}
+			if got.Version != 2000 || len(got.Items) != 2 {
+				t.Fatalf("got %+v", got)
+			}
+			if it, ok := got.Get("ARTIST"); !ok || string(it.Value) != "Alice" || it.Type() != ItemText {
+				t.Errorf("Get(ARTIST) got %+v, %v", it, ok)
+			}
+			if it, _ := got.Get("cover art (front)"); it.Type() != ItemBinary {
+				t.Errorf("cover item type got %d, want ItemBinary", it.Type())
+			}
+		})
+	}
+
+	if _, err := Read(bytes.NewReader(audio), int64(len(audio))); err != ErrNoTag {
+		t.Errorf("Read() without a tag got %v, want ErrNoTag", err)
+	}
+	broken := append(append([]byte{}, audio...), tag...)
+	binary.LittleEndian.PutUint32(broken[len(broken)-footerSize+16:], 5) // Элементов больше, чем есть.
+	if _, err := Read(bytes.NewReader(broken), int64(len(broken))); err == nil {
+		t.Error("Read() accepted a truncated tag")
+	}
+}
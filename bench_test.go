@@ -0,0 +1,101 @@
+package gocue
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// benchSmallCue — типичный CUE одного альбома.
+const benchSmallCue = `REM GENRE Jazz
+REM DATE 1959
+REM DISCID 3A0A5E05
+REM COMMENT "ExactAudioCopy v1.6"
+CATALOG 0074643135527
+PERFORMER "Miles Davis"
+TITLE "Kind of Blue"
+FILE "Miles Davis - Kind of Blue.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    PERFORMER "Miles Davis"
+    ISRC USSM15900113
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Freddie Freeloader"
+    PERFORMER "Miles Davis"
+    INDEX 00 09:20:40
+    INDEX 01 09:22:00
+  TRACK 03 AUDIO
+    TITLE "Blue in Green"
+    PERFORMER "Miles Davis"
+    INDEX 01 15:08:00
+  TRACK 04 AUDIO
+    TITLE "All Blues"
+    PERFORMER "Miles Davis"
+    INDEX 01 20:45:00
+  TRACK 05 AUDIO
+    TITLE "Flamenco Sketches"
+    PERFORMER "Miles Davis"
+    INDEX 01 32:20:00
+`
+
+// benchLargeCue строит CUE на 99 треков с файлом на каждый трек,
+// метаданными, REM и несколькими индексами.
+func benchLargeCue() string {
+	var b strings.Builder
+	b.WriteString("REM GENRE \"Classical\"\nPERFORMER \"Various Artists\"\nTITLE \"The Complete Collection\"\n")
+	for n := 1; n <= 99; n++ {
+		fmt.Fprintf(&b, "FILE \"%02d - Track number %d.flac\" WAVE\n", n, n)
+		fmt.Fprintf(&b, "  TRACK %02d AUDIO\n", n)
+		fmt.Fprintf(&b, "    TITLE \"Symphony No. %d in C minor, Op. %d: I. Allegro con brio\"\n", n, n*3)
+		fmt.Fprintf(&b, "    PERFORMER \"Orchestra %d\"\n    COMPOSER \"Composer %d\"\n", n, n)
+		fmt.Fprintf(&b, "    REM REPLAYGAIN_TRACK_GAIN -%d.%02d dB\n    FLAGS DCP\n", n%12, n)
+		b.WriteString("    INDEX 00 00:00:00\n    INDEX 01 00:02:00\n    INDEX 02 01:30:00\n")
+	}
+	return b.String()
+}
+
+// benchPathologicalCue строит CUE из мусорных строк: длинные строки
+// с кавычками внутри аргументов, лишние пробелы и неизвестные команды.
+func benchPathologicalCue() string {
+	var b strings.Builder
+	b.WriteString("FILE \"a.wav\" WAVE\n  TRACK 01 AUDIO\n")
+	for n := range 500 {
+		fmt.Fprintf(&b, "    x_junk%d   a\"b c\"d\t\t%s  \"\"  \n", n, strings.Repeat("word ", 40))
+		fmt.Fprintf(&b, "    title \"%s\"\n\n\n", strings.Repeat("é", 300))
+	}
+	b.WriteString("    INDEX 01 00:00:00\n")
+	return b.String()
+}
+
+func benchmarkParse(b *testing.B, cue string) {
+	p := &Parser{}
+	b.SetBytes(int64(len(cue)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := p.Parse(strings.NewReader(cue)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParse_Small(b *testing.B)        { benchmarkParse(b, benchSmallCue) }
+func BenchmarkParse_Large(b *testing.B)        { benchmarkParse(b, benchLargeCue()) }
+func BenchmarkParse_Pathological(b *testing.B) { benchmarkParse(b, benchPathologicalCue()) }
+
+// BenchmarkDecoder_Next measures the streaming decoder alone, reusing one
+// decoder for every pass as a batch job would.
+func BenchmarkDecoder_Next(b *testing.B) {
+	cue := benchLargeCue()
+	d := NewDecoder(nil)
+	b.SetBytes(int64(len(cue)))
+	b.ReportAllocs()
+	for b.Loop() {
+		d.Reset(strings.NewReader(cue))
+		for {
+			if _, err := d.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrDuplicateTrack возвращается при попытке добавить трек с номером,
+	// который уже используется в CUE sheet.
+	ErrDuplicateTrack = errors.New("duplicate track number")
+	// ErrInvalidNumber возвращается, если номер трека или индекса вне допустимого диапазона.
+	ErrInvalidNumber = errors.New("number out of range")
+)
+
+// NewCuesheet создает пустой CUE sheet для программного построения.
+func NewCuesheet() *Cuesheet {
+	return &Cuesheet{}
+}
+
+// AddFile добавляет в CUE sheet новый файл и возвращает его.
+// Тип файла приводится к верхнему регистру, как при разборе.
+func (c *Cuesheet) AddFile(name, fileType string) *File {
+	file := &File{Name: name, Type: strings.ToUpper(fileType), parentSheet: c}
+	c.Files = append(c.Files, file)
+	return file
+}
+
+// Relink заново устанавливает внутренние ссылки на родительские элементы
+// у всех файлов и треков. Его нужно вызвать после того, как структура была
+// изменена напрямую через поля Files или Tracks, иначе методы вроде
+// track.Duration() не увидят новые элементы.
+func (c *Cuesheet) Relink() {
+	for _, f := range c.Files {
+		f.parentSheet = c
+		for _, t := range f.Tracks {
+			t.parentFile = f
+		}
+	}
+}
+
+// AddTrack добавляет в файл новый трек с указанным номером (1-99) и типом.
+// Номер должен быть уникальным в пределах всего CUE sheet, если файл к нему
+// привязан, или в пределах файла в противном случае.
+func (f *File) AddTrack(number int, trackType string) (*Track, error) {
+	if number < 1 || number > 99 {
+		return nil, fmt.Errorf("track %d: %w", number, ErrInvalidNumber)
+	}
+
+	files := []*File{f}
+	if f.parentSheet != nil {
+		files = f.parentSheet.Files
+	}
+	for _, file := range files {
+		for _, t := range file.Tracks {
+			if t.Number == number {
+				return nil, fmt.Errorf("track %d: %w", number, ErrDuplicateTrack)
+			}
+		}
+	}
+
+	track := &Track{Number: number, Type: strings.ToUpper(trackType), parentFile: f}
+	f.Tracks = append(f.Tracks, track)
+	return track, nil
+}
+
+// SetIndex устанавливает время индекса с указанным номером (0-99).
+// Существующий индекс с тем же номером заменяется, новый вставляется перед
+// первым индексом с большим номером. У заменяемого
+// индекса сохраняется файл (Index.File), а точная позиция (Index.Precise) —
+// если она по-прежнему соответствует новому таймкоду.
+func (t *Track) SetIndex(number int, tc Timecode) error {
+	if number < 0 || number > 99 {
+		return fmt.Errorf("index %d: %w", number, ErrInvalidNumber)
+	}
+
+	// Поиск линейный: в разобранных файлах индексы могут идти не по порядку.
+	if i := slices.IndexFunc(t.Indices, func(idx Index) bool { return idx.Number == number }); i >= 0 {
+		idx := &t.Indices[i]
+		if idx.Precise > 0 && TimecodeFromDuration(idx.Precise, RoundFloor) != tc {
+			idx.Precise = 0
+		}
+		idx.Time = tc
+		return nil
+	}
+	pos := slices.IndexFunc(t.Indices, func(idx Index) bool { return idx.Number > number })
+	if pos < 0 {
+		pos = len(t.Indices)
+	}
+	t.Indices = slices.Insert(t.Indices, pos, Index{Number: number, Time: tc})
+	return nil
+}
@@ -0,0 +1,123 @@
+package gocue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBuild_BehavesLikeParsed checks that a hand-built sheet supports the same helpers as a parsed one.
+func TestBuild_BehavesLikeParsed(t *testing.T) {
+	sheet := NewCuesheet()
+	file := sheet.AddFile("album.wav", "wave")
+
+	track1, err := file.AddTrack(1, "audio")
+	if err != nil {
+		t.Fatalf("AddTrack(1) returned an unexpected error: %v", err)
+	}
+	track2, err := file.AddTrack(2, "AUDIO")
+	if err != nil {
+		t.Fatalf("AddTrack(2) returned an unexpected error: %v", err)
+	}
+	track1.SetIndex(1, Timecode{})
+	track2.SetIndex(1, Timecode{Minutes: 4})
+	track2.SetIndex(0, Timecode{Minutes: 3, Seconds: 58})
+
+	if file.Type != "WAVE" || track1.Type != "AUDIO" {
+		t.Errorf("types were not normalized: %s %s", file.Type, track1.Type)
+	}
+	if len(track2.Indices) != 2 || track2.Indices[0].Number != 0 || track2.Indices[1].Number != 1 {
+		t.Errorf("indices are not sorted: %+v", track2.Indices)
+	}
+	if got := track1.Duration().Minutes(); got != 4 {
+		t.Errorf("track 1 duration got %v minutes, want 4", got)
+	}
+
+	// Повторная установка заменяет индекс, а не добавляет новый.
+	track2.SetIndex(1, Timecode{Minutes: 5})
+	if len(track2.Indices) != 2 || track2.StartTime().Minutes != 5 {
+		t.Errorf("SetIndex() did not replace INDEX 01: %+v", track2.Indices)
+	}
+
+	if err := track1.SetIndex(100, Timecode{}); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("SetIndex(100) got error %v, want ErrInvalidNumber", err)
+	}
+}
+
+// TestFile_AddTrackValidation checks track number uniqueness across files.
+func TestFile_AddTrackValidation(t *testing.T) {
+	sheet := NewCuesheet()
+	if _, err := sheet.AddFile("1.wav", "WAVE").AddTrack(1, "AUDIO"); err != nil {
+		t.Fatalf("AddTrack(1) returned an unexpected error: %v", err)
+	}
+	second := sheet.AddFile("2.wav", "WAVE")
+	if _, err := second.AddTrack(1, "AUDIO"); !errors.Is(err, ErrDuplicateTrack) {
+		t.Errorf("AddTrack(1) in another file got error %v, want ErrDuplicateTrack", err)
+	}
+	if _, err := second.AddTrack(0, "AUDIO"); !errors.Is(err, ErrInvalidNumber) {
+		t.Errorf("AddTrack(0) got error %v, want ErrInvalidNumber", err)
+	}
+}
+
+// TestCuesheet_Relink checks that tracks appended directly become linked.
+func TestCuesheet_Relink(t *testing.T) {
+	sheet := NewCuesheet()
+	file := sheet.AddFile("a.wav", "WAVE")
+	file.Tracks = append(file.Tracks,
+		&Track{Number: 1, Indices: []Index{{Number: 1}}},
+		&Track{Number: 2, Indices: []Index{{Number: 1, Time: Timecode{Seconds: 30}}}},
+	)
+	if file.Tracks[0].Duration() != 0 {
+		t.Fatal("unlinked track unexpectedly reported a duration")
+	}
+	sheet.Relink()
+	if got := file.Tracks[0].Duration().Seconds(); got != 30 {
+		t.Errorf("track 1 duration after Relink got %vs, want 30s", got)
+	}
+}
+
+// TestTrack_SetIndexKeepsFile checks that replacing an index keeps its FILE and
+// its precise position when the latter still matches.
+func TestTrack_SetIndexKeepsFile(t *testing.T) {
+	cue := "FILE \"01.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 00 04:10:20\nFILE \"02.wav\" WAVE\nINDEX 01 00:00:00"
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	track2 := sheet.Files[1].Tracks[0]
+	if err := track2.SetIndex(0, Timecode{Minutes: 4, Seconds: 5}); err != nil {
+		t.Fatalf("SetIndex() returned an unexpected error: %v", err)
+	}
+	if track2.IndexFile(track2.Indices[0]) != sheet.Files[0] {
+		t.Errorf("SetIndex() moved INDEX 00 out of %s", sheet.Files[0].Name)
+	}
+	if got := track2.Indices[0].Time; got != (Timecode{Minutes: 4, Seconds: 5}) {
+		t.Errorf("SetIndex() got time %s", got)
+	}
+
+	track := &Track{Indices: []Index{{Number: 1, Time: Timecode{Seconds: 1, Frames: 39}, Precise: 1533 * time.Millisecond}}}
+	track.SetIndex(1, Timecode{Seconds: 1, Frames: 39})
+	if track.Indices[0].Precise != 1533*time.Millisecond {
+		t.Errorf("SetIndex() with the same time dropped Precise")
+	}
+	track.SetIndex(1, Timecode{Seconds: 2})
+	if track.Indices[0].Precise != 0 {
+		t.Errorf("SetIndex() with a new time kept a stale Precise %v", track.Indices[0].Precise)
+	}
+}
+
+// TestTrack_SetIndexUnsorted checks replacing an index on a parsed track whose indices are out of order.
+func TestTrack_SetIndexUnsorted(t *testing.T) {
+	sheet, err := Parse(strings.NewReader("FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:02:00\nINDEX 00 00:00:00"))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	track := sheet.Track(1)
+	if err := track.SetIndex(0, Timecode{Seconds: 1}); err != nil {
+		t.Fatalf("SetIndex() returned an unexpected error: %v", err)
+	}
+	if len(track.Indices) != 2 || track.Indices[1].Time != (Timecode{Seconds: 1}) {
+		t.Errorf("SetIndex() got indices %+v, want INDEX 00 replaced", track.Indices)
+	}
+}
@@ -0,0 +1,409 @@
+// Package cdtext читает и создает двоичные данные CD-TEXT — файлы, на которые
+// ссылается команда CDTEXTFILE (.cdt), и данные, считанные с диска командой READ TOC.
+//
+// CD-TEXT состоит из пакетов по 18 байт: тип, номер трека, порядковый номер,
+// номер блока и позиция символа, 12 байт данных и CRC. Диск может содержать
+// до восьми блоков — по одному на язык.
+package cdtext
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// PackSize — размер пакета CD-TEXT в байтах.
+const PackSize = 18
+
+// payloadSize — размер данных в пакете.
+const payloadSize = 12
+
+// maxBlocks — максимальное число языковых блоков.
+const maxBlocks = 8
+
+// PackType — тип пакета CD-TEXT.
+type PackType byte
+
+// Типы пакетов CD-TEXT.
+const (
+	PackTitle      PackType = 0x80
+	PackPerformer  PackType = 0x81
+	PackSongwriter PackType = 0x82
+	PackComposer   PackType = 0x83
+	PackArranger   PackType = 0x84
+	PackMessage    PackType = 0x85
+	PackDiscID     PackType = 0x86
+	PackGenre      PackType = 0x87
+	PackTOC        PackType = 0x88
+	PackTOC2       PackType = 0x89
+	PackClosed     PackType = 0x8D
+	PackCode       PackType = 0x8E // UPC/EAN диска и ISRC треков.
+	PackSizeInfo   PackType = 0x8F
+)
+
+// Charset — кодировка текста блока.
+type Charset byte
+
+// Кодировки, определенные для CD-TEXT.
+const (
+	CharsetISO8859_1 Charset = 0x00
+	CharsetASCII     Charset = 0x01 // ISO 646, 7 бит.
+	CharsetMSJIS     Charset = 0x80 // Двухбайтовая кодировка (Shift-JIS).
+	CharsetKorean    Charset = 0x81
+	CharsetMandarin  Charset = 0x82
+)
+
+// String возвращает название кодировки.
+func (cs Charset) String() string {
+	switch cs {
+	case CharsetISO8859_1:
+		return "ISO-8859-1"
+	case CharsetASCII:
+		return "ASCII"
+	case CharsetMSJIS:
+		return "MS-JIS"
+	case CharsetKorean:
+		return "Korean"
+	case CharsetMandarin:
+		return "Mandarin"
+	}
+	return fmt.Sprintf("charset 0x%02X", byte(cs))
+}
+
+// Language — код языка блока по спецификации EBU Tech 3258.
+type Language byte
+
+// Часто встречающиеся коды языков.
+const (
+	LanguageUnknown  Language = 0x00
+	LanguageGerman   Language = 0x08
+	LanguageEnglish  Language = 0x09
+	LanguageSpanish  Language = 0x0A
+	LanguageFrench   Language = 0x0F
+	LanguageItalian  Language = 0x15
+	LanguageDutch    Language = 0x1D
+	LanguageChinese  Language = 0x75
+	LanguageKorean   Language = 0x65
+	LanguageJapanese Language = 0x69
+	LanguageRussian  Language = 0x56
+)
+
+// Fields — текстовые поля диска или трека.
+type Fields struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Composer   string
+	Arranger   string
+	Message    string
+	Code       string // UPC/EAN для диска, ISRC для трека.
+}
+
+// textFields связывает текстовые типы пакетов с полями Fields.
+var textFields = []struct {
+	typ   PackType
+	field func(*Fields) *string
+}{
+	{PackTitle, func(f *Fields) *string { return &f.Title }},
+	{PackPerformer, func(f *Fields) *string { return &f.Performer }},
+	{PackSongwriter, func(f *Fields) *string { return &f.Songwriter }},
+	{PackComposer, func(f *Fields) *string { return &f.Composer }},
+	{PackArranger, func(f *Fields) *string { return &f.Arranger }},
+	{PackMessage, func(f *Fields) *string { return &f.Message }},
+	{PackCode, func(f *Fields) *string { return &f.Code }},
+}
+
+// Block — один языковой блок CD-TEXT.
+type Block struct {
+	Number     int // Номер блока, 0-7.
+	Language   Language
+	Charset    Charset
+	FirstTrack int
+	LastTrack  int
+	Copyright  byte // Флаги защиты из блока размеров.
+
+	Disc      Fields
+	Tracks    map[int]Fields // Поля треков по номеру трека.
+	DiscID    string
+	Genre     Genre
+	GenreText string // Дополнительное описание жанра.
+	TOC       []byte // Данные пакетов TOC (0x88) без разбора.
+	TOC2      []byte // Данные пакетов TOC2 (0x89) без разбора.
+
+	// PackCounts — число пакетов каждого типа 0x80-0x8F по блоку размеров.
+	PackCounts [16]int
+	// HasSizeInfo сообщает, что блок содержал пакеты размеров (0x8F).
+	HasSizeInfo bool
+}
+
+// Track возвращает поля трека number; отсутствующий трек дает пустые поля.
+func (b *Block) Track(number int) Fields {
+	return b.Tracks[number]
+}
+
+// CDText — все блоки CD-TEXT в порядке номеров.
+type CDText struct {
+	Blocks []*Block
+}
+
+// Block возвращает блок для языка lang или nil, если такого блока нет.
+func (t *CDText) Block(lang Language) *Block {
+	for _, b := range t.Blocks {
+		if b.Language == lang {
+			return b
+		}
+	}
+	return nil
+}
+
+// ErrCRC возвращается, если контрольная сумма пакета не совпадает.
+var ErrCRC = errors.New("CD-TEXT pack CRC mismatch")
+
+// DecodeFunc переводит текст в кодировке cs в UTF-8.
+type DecodeFunc func(cs Charset, text []byte) (string, error)
+
+// Parser разбирает данные CD-TEXT.
+type Parser struct {
+	// Decode переводит в UTF-8 текст в кодировках, отличных от ISO-8859-1
+	// и ASCII, например MS-JIS. Если он не задан, такой текст сохраняется
+	// без перекодирования.
+	Decode DecodeFunc
+
+	// IgnoreCRC отключает проверку контрольных сумм пакетов. Пакеты с нулевой
+	// CRC принимаются всегда: некоторые приводы не возвращают ее.
+	IgnoreCRC bool
+}
+
+// Parse разбирает данные CD-TEXT с настройками по умолчанию.
+func Parse(data []byte) (*CDText, error) {
+	return (&Parser{}).Parse(data)
+}
+
+// ReadFile читает и разбирает файл CD-TEXT.
+func ReadFile(path string) (*CDText, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse разбирает данные CD-TEXT: последовательность 18-байтовых пакетов,
+// возможно с 4-байтовым заголовком ответа READ TOC и завершающим нулевым байтом,
+// которые добавляют некоторые программы записи.
+func (p *Parser) Parse(data []byte) (*CDText, error) {
+	switch len(data) % PackSize {
+	case 0:
+	case 1: // Завершающий нулевой байт.
+		data = data[:len(data)-1]
+	case 4: // Заголовок: длина данных (2 байта) и 2 резервных байта.
+		data = data[4:]
+	case 5:
+		data = data[4 : len(data)-1]
+	default:
+		return nil, fmt.Errorf("CD-TEXT size %d is not a multiple of %d", len(data), PackSize)
+	}
+	if len(data) == 0 {
+		return nil, errors.New("no CD-TEXT packs")
+	}
+
+	var raw [maxBlocks]map[PackType][][]byte // Пакеты по блокам и типам в порядке следования.
+	for i := 0; i < len(data); i += PackSize {
+		pack := data[i : i+PackSize]
+		if !p.IgnoreCRC && (pack[16] != 0 || pack[17] != 0) && crc(pack[:16]) != uint16(pack[16])<<8|uint16(pack[17]) {
+			return nil, fmt.Errorf("%w: pack %d", ErrCRC, i/PackSize)
+		}
+		typ := PackType(pack[0])
+		if typ < 0x80 || typ > 0x8F {
+			return nil, fmt.Errorf("pack %d: invalid pack type 0x%02X", i/PackSize, pack[0])
+		}
+		block := int(pack[3] >> 4 & 0x07)
+		if raw[block] == nil {
+			raw[block] = make(map[PackType][][]byte)
+		}
+		raw[block][typ] = append(raw[block][typ], pack)
+	}
+
+	t := &CDText{}
+	for n, packs := range raw {
+		if packs == nil {
+			continue
+		}
+		b, err := p.decodeBlock(n, packs)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", n, err)
+		}
+		t.Blocks = append(t.Blocks, b)
+	}
+	return t, nil
+}
+
+// decodeBlock собирает поля одного языкового блока.
+func (p *Parser) decodeBlock(n int, packs map[PackType][][]byte) (*Block, error) {
+	b := &Block{Number: n, Tracks: make(map[int]Fields)}
+	if info := packs[PackSizeInfo]; len(info) > 0 {
+		if err := b.decodeSizeInfo(info); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tx := range textFields {
+		// UPC и ISRC всегда записываются в ASCII.
+		charset := b.Charset
+		if tx.typ == PackCode {
+			charset = CharsetASCII
+		}
+		values, err := p.decodeStrings(packs[tx.typ], charset)
+		if err != nil {
+			return nil, fmt.Errorf("pack type 0x%02X: %w", byte(tx.typ), err)
+		}
+		for track, v := range values {
+			if track == 0 {
+				*tx.field(&b.Disc) = v
+				continue
+			}
+			f := b.Tracks[track]
+			*tx.field(&f) = v
+			b.Tracks[track] = f
+		}
+	}
+
+	ids, err := p.decodeStrings(packs[PackDiscID], b.Charset)
+	if err != nil {
+		return nil, fmt.Errorf("pack type 0x86: %w", err)
+	}
+	b.DiscID = ids[0]
+	if genre := payload(packs[PackGenre]); len(genre) >= 2 {
+		b.Genre = Genre(uint16(genre[0])<<8 | uint16(genre[1]))
+		text, _, _ := cut(genre[2:], isDoubleByte(b.Charset))
+		if b.GenreText, err = p.decode(b.Charset, text); err != nil {
+			return nil, fmt.Errorf("pack type 0x87: %w", err)
+		}
+	}
+	b.TOC = payload(packs[PackTOC])
+	b.TOC2 = payload(packs[PackTOC2])
+	return b, nil
+}
+
+// decodeSizeInfo разбирает блок размеров: три пакета 0x8F по 12 байт.
+func (b *Block) decodeSizeInfo(packs [][]byte) error {
+	info := payload(packs)
+	if len(info) < 36 {
+		return errors.New("truncated size information")
+	}
+	b.HasSizeInfo = true
+	b.Charset = Charset(info[0])
+	b.FirstTrack, b.LastTrack = int(info[1]), int(info[2])
+	b.Copyright = info[3]
+	for i := range b.PackCounts {
+		b.PackCounts[i] = int(info[4+i])
+	}
+	// Байты 20-27 — последние порядковые номера блоков, 28-35 — их языки.
+	b.Language = Language(info[28+b.Number])
+	return nil
+}
+
+// decodeStrings собирает строки из текстовых пакетов одного типа и возвращает
+// их по номерам треков. Строки идут подряд начиная с трека первого пакета
+// и завершаются нулем (двумя нулями для двухбайтовых кодировок). Строка из
+// одного символа табуляции означает "как у предыдущего трека".
+func (p *Parser) decodeStrings(packs [][]byte, charset Charset) (map[int]string, error) {
+	values := make(map[int]string)
+	if len(packs) == 0 {
+		return values, nil
+	}
+	data := payload(packs)
+	double := packs[0][3]&0x80 != 0
+	track := int(packs[0][1] & 0x7f)
+	prev := ""
+	for len(data) > 0 {
+		s, rest, ok := cut(data, double)
+		if !ok && allZero(s) {
+			break // Заполнение нулями в конце последнего пакета.
+		}
+		var v string
+		if string(s) == "\t" || string(s) == "\t\t" {
+			v = prev
+		} else {
+			var err error
+			if v, err = p.decode(charset, s); err != nil {
+				return nil, err
+			}
+		}
+		if v != "" {
+			values[track] = v
+		}
+		prev = v
+		track++
+		data = rest
+	}
+	return values, nil
+}
+
+// decode переводит текст в UTF-8.
+func (p *Parser) decode(charset Charset, b []byte) (string, error) {
+	switch charset {
+	case CharsetISO8859_1, CharsetASCII:
+		// Каждый байт ISO-8859-1 совпадает с кодовой точкой Unicode.
+		r := make([]rune, len(b))
+		for i, c := range b {
+			r[i] = rune(c)
+		}
+		return string(r), nil
+	}
+	if p.Decode != nil {
+		return p.Decode(charset, b)
+	}
+	return string(b), nil
+}
+
+// payload склеивает данные пакетов.
+func payload(packs [][]byte) []byte {
+	var data []byte
+	for _, pack := range packs {
+		data = append(data, pack[4:4+payloadSize]...)
+	}
+	return data
+}
+
+// cut отделяет от data строку до терминатора: одного нулевого байта или,
+// для двухбайтовых кодировок, двух. ok равен false, если терминатора нет.
+func cut(data []byte, double bool) (s, rest []byte, ok bool) {
+	step := 1
+	if double {
+		step = 2
+	}
+	for i := 0; i+step <= len(data); i += step {
+		if data[i] == 0 && (!double || data[i+1] == 0) {
+			return data[:i], data[i+step:], true
+		}
+	}
+	return data, nil, false
+}
+
+// allZero сообщает, что все байты равны нулю.
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// crc вычисляет CRC-16/CCITT (полином 0x1021, начальное значение 0) данных пакета
+// и инвертирует результат, как того требует спецификация CD-TEXT.
+func crc(data []byte) uint16 {
+	var c uint16
+	for _, b := range data {
+		c ^= uint16(b) << 8
+		for range 8 {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x1021
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return ^c
+}
@@ -0,0 +1,189 @@
+package cdtext
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/theurs/gocue"
+)
+
+// packWriter собирает пакеты CD-TEXT для тестов.
+type packWriter struct {
+	data []byte
+	seq  int
+}
+
+// text добавляет пакеты типа typ со строками для треков начиная с 0.
+func (w *packWriter) text(block int, typ PackType, values ...string) {
+	var data []byte
+	var tracks []int // Трек каждого байта data.
+	for track, v := range values {
+		for range len(v) + 1 {
+			tracks = append(tracks, track)
+		}
+		data = append(data, v...)
+		data = append(data, 0)
+	}
+	for len(data)%payloadSize != 0 {
+		data = append(data, 0)
+		tracks = append(tracks, tracks[len(tracks)-1])
+	}
+	for i := 0; i < len(data); i += payloadSize {
+		// Позиция символа: сколько байт текущей строки было в предыдущих пакетах.
+		pos := 0
+		for j := i - 1; j >= 0 && tracks[j] == tracks[i]; j-- {
+			pos++
+		}
+		w.pack(typ, tracks[i], block<<4|min(pos, 15), data[i:i+payloadSize])
+	}
+}
+
+// pack добавляет один пакет с корректной CRC.
+func (w *packWriter) pack(typ PackType, track, flags int, payload []byte) {
+	p := []byte{byte(typ), byte(track), byte(w.seq), byte(flags)}
+	p = append(p, payload...)
+	p = append(p, make([]byte, 4+payloadSize-len(p))...)
+	c := crc(p)
+	w.data = append(w.data, append(p, byte(c>>8), byte(c))...)
+	w.seq++
+}
+
+// sizeInfo добавляет три пакета размеров для блока block.
+func (w *packWriter) sizeInfo(block int, charset Charset, first, last int, lang Language) {
+	info := make([]byte, 36)
+	info[0], info[1], info[2] = byte(charset), byte(first), byte(last)
+	info[28+block] = byte(lang)
+	for i := range 3 {
+		w.pack(PackSizeInfo, i, block<<4, info[i*12:i*12+12])
+	}
+}
+
+// TestParse checks decoding of text, codes, genre and size information.
+func TestParse(t *testing.T) {
+	w := &packWriter{}
+	w.text(0, PackTitle, "Kind of Blue", "So What", "Freddie Freeloader")
+	w.text(0, PackPerformer, "Miles Davis", "", "\t")
+	w.text(0, PackComposer, "", "Miles Davis")
+	w.text(0, PackCode, "0074643135527", "USSM15900113")
+	w.text(0, PackDiscID, "XY12345")
+	w.pack(PackGenre, 0, 0, append([]byte{0, byte(GenreJazz)}, "Modal\x00"...))
+	w.sizeInfo(0, CharsetISO8859_1, 1, 2, LanguageEnglish)
+	w.text(1, PackTitle, "Kind of Blue (\xe9dition)")
+	w.sizeInfo(1, CharsetISO8859_1, 1, 2, LanguageFrench)
+
+	cdt, err := Parse(w.data)
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if len(cdt.Blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(cdt.Blocks))
+	}
+	b := cdt.Block(LanguageEnglish)
+	if b == nil || b.FirstTrack != 1 || b.LastTrack != 2 || !b.HasSizeInfo {
+		t.Fatalf("English block got %+v", b)
+	}
+	wantDisc := Fields{Title: "Kind of Blue", Performer: "Miles Davis", Code: "0074643135527"}
+	if b.Disc != wantDisc {
+		t.Errorf("Disc got %+v, want %+v", b.Disc, wantDisc)
+	}
+	want1 := Fields{Title: "So What", Composer: "Miles Davis", Code: "USSM15900113"}
+	if got := b.Track(1); got != want1 {
+		t.Errorf("Track(1) got %+v, want %+v", got, want1)
+	}
+	if got := b.Track(2); got.Title != "Freddie Freeloader" || got.Performer != "" {
+		t.Errorf("Track(2) got %+v", got)
+	}
+	if b.DiscID != "XY12345" || b.Genre != GenreJazz || b.GenreText != "Modal" || b.Genre.String() != "Jazz" {
+		t.Errorf("got disc id %q, genre %v %q", b.DiscID, b.Genre, b.GenreText)
+	}
+	if fr := cdt.Block(LanguageFrench); fr == nil || fr.Disc.Title != "Kind of Blue (édition)" {
+		t.Errorf("French block got %+v", fr)
+	}
+}
+
+// TestParse_TabRepeatsPrevious checks the TAB shorthand for repeated values.
+func TestParse_TabRepeatsPrevious(t *testing.T) {
+	w := &packWriter{}
+	w.text(0, PackPerformer, "", "Alice", "\t", "Bob")
+	cdt, err := Parse(w.data)
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	var got []string
+	for n := 1; n <= 3; n++ {
+		got = append(got, cdt.Blocks[0].Track(n).Performer)
+	}
+	if !slices.Equal(got, []string{"Alice", "Alice", "Bob"}) {
+		t.Errorf("got performers %v", got)
+	}
+}
+
+// TestParse_Framing checks the READ TOC header, the trailing byte and CRC errors.
+func TestParse_Framing(t *testing.T) {
+	w := &packWriter{}
+	w.text(0, PackTitle, "Album", "One")
+	packs := w.data
+
+	header := append([]byte{0, byte(len(packs) + 2), 0, 0}, packs...)
+	for _, data := range [][]byte{header, append(slices.Clone(packs), 0), append(slices.Clone(header), 0)} {
+		if cdt, err := Parse(data); err != nil || cdt.Blocks[0].Disc.Title != "Album" {
+			t.Errorf("Parse(%d bytes) got %v", len(data), err)
+		}
+	}
+
+	broken := slices.Clone(packs)
+	broken[5] ^= 0xff
+	if _, err := Parse(broken); !errors.Is(err, ErrCRC) {
+		t.Errorf("Parse() with a bad CRC got %v, want ErrCRC", err)
+	}
+	if _, err := (&Parser{IgnoreCRC: true}).Parse(broken); err != nil {
+		t.Errorf("Parse() with IgnoreCRC returned an unexpected error: %v", err)
+	}
+	if _, err := Parse(packs[:20]); err == nil {
+		t.Error("Parse() accepted a partial pack")
+	}
+}
+
+// TestMerge checks that CD-TEXT only fills values missing from the cue.
+func TestMerge(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(`
+TITLE "From Cue"
+REM GENRE Bebop
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Cue Two"
+    INDEX 01 03:00:00
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &packWriter{}
+	w.text(0, PackTitle, "From CD-TEXT", "One", "Two")
+	w.text(0, PackPerformer, "Miles Davis")
+	w.text(0, PackArranger, "", "Gil Evans")
+	w.text(0, PackCode, "0074643135527", "USSM15900113")
+	w.pack(PackGenre, 0, 0, []byte{0, byte(GenreJazz)})
+	cdt, err := Parse(w.data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdt.Merge(sheet)
+
+	if sheet.Title != "From Cue" || sheet.Performer != "Miles Davis" || sheet.Catalog != "0074643135527" {
+		t.Errorf("got disc %q / %q / %q", sheet.Title, sheet.Performer, sheet.Catalog)
+	}
+	if sheet.Genre != "" {
+		t.Errorf("GENRE got %q, want REM GENRE to take precedence", sheet.Genre)
+	}
+	one, two := sheet.Track(1), sheet.Track(2)
+	if one.Title != "One" || one.ISRC != "USSM15900113" || two.Title != "Cue Two" {
+		t.Errorf("got tracks %q/%q and %q", one.Title, one.ISRC, two.Title)
+	}
+	if one.Arranger != "Gil Evans" {
+		t.Errorf("ARRANGER got %q", one.Arranger)
+	}
+}
@@ -0,0 +1,391 @@
+package cdtext
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/theurs/gocue"
+)
+
+// maxPacks — максимальное число пакетов в блоке: порядковый номер занимает байт.
+const maxPacks = 256
+
+// maxFieldLength — рекомендуемая спецификацией длина одного поля в байтах.
+const maxFieldLength = 160
+
+// ErrCapacity возвращается, если данные блока не помещаются в CD-TEXT
+// даже после отбрасывания необязательных полей.
+var ErrCapacity = errors.New("CD-TEXT capacity exceeded")
+
+// Warning — предупреждение, выданное при кодировании.
+type Warning struct {
+	Block int // Номер блока.
+	Track int // Номер трека; 0 — диск.
+	Type  PackType
+	Msg   string
+}
+
+// String возвращает текст предупреждения.
+func (w Warning) String() string {
+	return fmt.Sprintf("block %d, track %d, pack type 0x%02X: %s", w.Block, w.Track, byte(w.Type), w.Msg)
+}
+
+// EncodeFunc переводит текст из UTF-8 в кодировку cs.
+type EncodeFunc func(cs Charset, text string) ([]byte, error)
+
+// Encoder кодирует CD-TEXT в пакеты.
+type Encoder struct {
+	// EncodeText переводит текст в кодировки, отличные от ISO-8859-1 и ASCII,
+	// например MS-JIS. Без него блоки в таких кодировках не кодируются.
+	EncodeText EncodeFunc
+}
+
+// Encode кодирует CD-TEXT с настройками по умолчанию.
+func Encode(t *CDText) ([]byte, []Warning, error) {
+	return (&Encoder{}).Encode(t)
+}
+
+// WriteFile кодирует CD-TEXT и записывает пакеты в файл path, на который
+// затем можно сослаться командой CDTEXTFILE.
+func WriteFile(path string, t *CDText) ([]Warning, error) {
+	data, warnings, err := Encode(t)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, os.WriteFile(path, data, 0o644)
+}
+
+// dropOrder — поля, которые отбрасываются по очереди, если блок не помещается
+// в 256 пакетов.
+var dropOrder = []PackType{PackMessage, PackArranger, PackComposer, PackSongwriter}
+
+// Encode кодирует блоки t в последовательность пакетов с CRC. Блоки получают
+// номера по порядку в t.Blocks (поле Block.Number не используется); в каждый
+// блок добавляются три пакета размеров (0x8F) со сведениями обо всех блоках.
+//
+// Кодирование не прерывается из-за проблем, которые не делают данные
+// недействительными: поля длиннее 160 байт, символы, которых нет в кодировке
+// блока (они заменяются на "?"), и отброшенные поля попадают в предупреждения.
+// Если блок не помещается в 256 пакетов, из него по очереди отбрасываются
+// MESSAGE, ARRANGER, COMPOSER и SONGWRITER; если и этого мало, возвращается ErrCapacity.
+func (e *Encoder) Encode(t *CDText) ([]byte, []Warning, error) {
+	if len(t.Blocks) == 0 {
+		return nil, nil, errors.New("no CD-TEXT blocks")
+	}
+	if len(t.Blocks) > maxBlocks {
+		return nil, nil, fmt.Errorf("%w: %d blocks, at most %d", ErrCapacity, len(t.Blocks), maxBlocks)
+	}
+
+	var warnings []Warning
+	encoded := make([][][]byte, len(t.Blocks)) // Пакеты блоков без блока размеров.
+	for n, b := range t.Blocks {
+		drop := make(map[PackType]bool)
+		for {
+			be := &blockEncoder{enc: e, block: b, number: n, drop: drop}
+			packs, err := be.packs()
+			if err != nil {
+				return nil, nil, fmt.Errorf("block %d: %w", n, err)
+			}
+			if len(packs)+3 <= maxPacks {
+				warnings = append(warnings, be.warnings...)
+				encoded[n] = packs
+				break
+			}
+			if len(drop) == len(dropOrder) {
+				return nil, warnings, fmt.Errorf("%w: block %d needs %d packs, at most %d", ErrCapacity, n, len(packs)+3, maxPacks)
+			}
+			typ := dropOrder[len(drop)]
+			drop[typ] = true
+			warnings = append(warnings, Warning{Block: n, Type: typ, Msg: "dropped to fit into 256 packs"})
+		}
+	}
+
+	// Блок размеров каждого блока описывает все блоки: последние порядковые
+	// номера и языки.
+	var lastSeq, langs [maxBlocks]byte
+	for n, b := range t.Blocks {
+		lastSeq[n] = byte(len(encoded[n]) + 2)
+		langs[n] = byte(b.Language)
+	}
+
+	var data []byte
+	for n, b := range t.Blocks {
+		packs := encoded[n]
+		var counts [16]int
+		for _, p := range packs {
+			counts[p[0]-0x80]++
+		}
+		counts[PackSizeInfo-0x80] = 3
+		first, last := b.trackRange()
+
+		info := make([]byte, 0, 36)
+		info = append(info, byte(b.Charset), byte(first), byte(last), b.Copyright)
+		for _, c := range counts {
+			info = append(info, byte(c))
+		}
+		info = append(info, lastSeq[:]...)
+		info = append(info, langs[:]...)
+		for i := range 3 {
+			packs = append(packs, newPack(PackSizeInfo, i, n, 0, false, info[i*payloadSize:(i+1)*payloadSize]))
+		}
+		for seq, p := range packs {
+			p[2] = byte(seq)
+			c := crc(p[:16])
+			p[16], p[17] = byte(c>>8), byte(c)
+			data = append(data, p...)
+		}
+	}
+	return data, warnings, nil
+}
+
+// trackRange возвращает первый и последний номер трека блока: из блока
+// размеров, если он заполнен, иначе по трекам с текстом.
+func (b *Block) trackRange() (first, last int) {
+	if b.FirstTrack > 0 && b.LastTrack >= b.FirstTrack {
+		return b.FirstTrack, b.LastTrack
+	}
+	for n := range b.Tracks {
+		if first == 0 || n < first {
+			first = n
+		}
+		last = max(last, n)
+	}
+	return max(first, 1), max(last, first, 1)
+}
+
+// blockEncoder кодирует пакеты одного блока.
+type blockEncoder struct {
+	enc      *Encoder
+	block    *Block
+	number   int
+	drop     map[PackType]bool
+	warnings []Warning
+}
+
+// packs возвращает пакеты блока, кроме пакетов размеров, без порядковых
+// номеров и CRC.
+func (be *blockEncoder) packs() ([][]byte, error) {
+	b := be.block
+	first, last := b.trackRange()
+	var packs [][]byte
+	for _, tx := range textFields {
+		if be.drop[tx.typ] {
+			continue
+		}
+		values := []string{*tx.field(&b.Disc)}
+		for n := first; n <= last; n++ {
+			f := b.Tracks[n]
+			values = append(values, *tx.field(&f))
+		}
+		p, err := be.text(tx.typ, first, values)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, p...)
+
+		// Пакеты 0x86 и 0x87 идут после текстовых полей треков, но до UPC/ISRC.
+		if tx.typ == PackMessage {
+			if p, err = be.text(PackDiscID, first, []string{b.DiscID}); err != nil {
+				return nil, err
+			}
+			packs = append(packs, p...)
+			if p, err = be.genre(); err != nil {
+				return nil, err
+			}
+			packs = append(packs, p...)
+			packs = append(packs, be.binary(PackTOC, b.TOC)...)
+			packs = append(packs, be.binary(PackTOC2, b.TOC2)...)
+		}
+	}
+	return packs, nil
+}
+
+// text кодирует строки одного типа: values[0] относится к диску, далее
+// треки начиная с first. Если все строки пусты, пакеты не создаются.
+func (be *blockEncoder) text(typ PackType, first int, values []string) ([][]byte, error) {
+	if strings.Join(values, "") == "" {
+		return nil, nil
+	}
+	charset := be.block.Charset
+	if typ == PackCode {
+		charset = CharsetASCII // UPC и ISRC всегда записываются в ASCII.
+	}
+	double := isDoubleByte(charset)
+	width := 1
+	if double {
+		width = 2
+	}
+
+	var data []byte
+	var owners []int // Трек, которому принадлежит каждый байт data.
+	for i, v := range values {
+		track := 0
+		if i > 0 {
+			track = first + i - 1
+		}
+		var s []byte
+		if i > 1 && v != "" && v == values[i-1] {
+			s = []byte(strings.Repeat("\t", width)) // "Как у предыдущего трека".
+		} else {
+			var err error
+			if s, err = be.encode(charset, typ, track, v); err != nil {
+				return nil, err
+			}
+		}
+		s = append(s, make([]byte, width)...)
+		data = append(data, s...)
+		for range s {
+			owners = append(owners, track)
+		}
+	}
+
+	var packs [][]byte
+	for i := 0; i < len(data); i += payloadSize {
+		end := min(i+payloadSize, len(data))
+		// Позиция символа — сколько символов текущей строки было в предыдущих пакетах.
+		pos := 0
+		for j := i - 1; j >= 0 && owners[j] == owners[i]; j-- {
+			pos++
+		}
+		packs = append(packs, newPack(typ, owners[i], be.number, min(pos/width, 15), double, data[i:end]))
+	}
+	return packs, nil
+}
+
+// genre кодирует пакет жанра: код и необязательное описание.
+func (be *blockEncoder) genre() ([][]byte, error) {
+	b := be.block
+	if b.Genre == GenreUnused && b.GenreText == "" {
+		return nil, nil
+	}
+	data := []byte{byte(b.Genre >> 8), byte(b.Genre)}
+	text, err := be.encode(b.Charset, PackGenre, 0, b.GenreText)
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, text...)
+	if isDoubleByte(b.Charset) {
+		data = append(data, 0)
+	}
+	return be.binary(PackGenre, append(data, 0)), nil
+}
+
+// binary разбивает двоичные данные на пакеты диска.
+func (be *blockEncoder) binary(typ PackType, data []byte) [][]byte {
+	var packs [][]byte
+	for i := 0; i < len(data); i += payloadSize {
+		packs = append(packs, newPack(typ, 0, be.number, 0, false, data[i:min(i+payloadSize, len(data))]))
+	}
+	return packs
+}
+
+// encode переводит строку в кодировку charset и проверяет ее длину.
+func (be *blockEncoder) encode(charset Charset, typ PackType, track int, s string) ([]byte, error) {
+	warn := func(msg string) {
+		be.warnings = append(be.warnings, Warning{Block: be.number, Track: track, Type: typ, Msg: msg})
+	}
+
+	var out []byte
+	switch charset {
+	case CharsetISO8859_1, CharsetASCII:
+		limit := rune(0xff)
+		if charset == CharsetASCII {
+			limit = 0x7f
+		}
+		replaced := false
+		for _, r := range s {
+			if r > limit || r == utf8.RuneError || r == 0 {
+				r, replaced = '?', true
+			}
+			out = append(out, byte(r))
+		}
+		if replaced {
+			warn(fmt.Sprintf("characters not representable in %s replaced with '?'", charset))
+		}
+	default:
+		if be.enc.EncodeText == nil {
+			return nil, fmt.Errorf("no encoder for %s", charset)
+		}
+		var err error
+		if out, err = be.enc.EncodeText(charset, s); err != nil {
+			return nil, err
+		}
+	}
+	if len(out) > maxFieldLength {
+		warn(fmt.Sprintf("text is %d bytes long, more than the recommended %d", len(out), maxFieldLength))
+	}
+	return out, nil
+}
+
+// newPack создает пакет без порядкового номера и CRC; данные дополняются нулями.
+func newPack(typ PackType, track, block, pos int, double bool, payload []byte) []byte {
+	flags := byte(block&0x07)<<4 | byte(pos&0x0f)
+	if double {
+		flags |= 0x80
+	}
+	p := make([]byte, PackSize)
+	p[0], p[1], p[3] = byte(typ), byte(track), flags
+	copy(p[4:4+payloadSize], payload)
+	return p
+}
+
+// isDoubleByte сообщает, что кодировка двухбайтовая.
+func isDoubleByte(cs Charset) bool {
+	return cs == CharsetMSJIS || cs == CharsetKorean || cs == CharsetMandarin
+}
+
+// NewBlock создает блок CD-TEXT на языке lang в кодировке charset из данных
+// CUE sheet c: TITLE, PERFORMER, SONGWRITER, COMPOSER, ARRANGER и MESSAGE
+// диска и треков, UPC_EAN (или CATALOG), ISRC треков, GENRE и DISC_ID.
+// Если команды GENRE нет, жанр берется из REM GENRE. Жанр, совпадающий
+// с одним из стандартных названий, записывается кодом, иначе — описанием
+// с кодом GenreNotDefined.
+func NewBlock(c *gocue.Cuesheet, lang Language, charset Charset) *Block {
+	b := &Block{Language: lang, Charset: charset, Tracks: make(map[int]Fields), DiscID: c.DiscID}
+	b.Disc = Fields{
+		Title:      c.Title,
+		Performer:  c.Performer,
+		Songwriter: c.Songwriter,
+		Composer:   c.Composer,
+		Arranger:   c.Arranger,
+		Message:    c.Message,
+		Code:       cmp.Or(c.UPCEAN, c.Catalog),
+	}
+	genre := c.Genre
+	if genre == "" {
+		genre, _ = c.RemValue("GENRE")
+	}
+	if genre != "" {
+		b.Genre, b.GenreText = GenreNotDefined, genre
+		for code, name := range genreNames {
+			if code > 0 && strings.EqualFold(name, genre) {
+				b.Genre, b.GenreText = Genre(code), ""
+			}
+		}
+	}
+
+	for _, t := range c.Tracks() {
+		if t.Number < 1 || t.Number > 99 {
+			continue // Скрытый трек 00 в CD-TEXT не описывается.
+		}
+		f := Fields{
+			Title:      t.Title,
+			Performer:  t.Performer,
+			Songwriter: t.Songwriter,
+			Composer:   t.Composer,
+			Arranger:   t.Arranger,
+			Message:    t.Message,
+			Code:       strings.ToUpper(strings.ReplaceAll(t.ISRC, "-", "")),
+		}
+		b.Tracks[t.Number] = f
+		if b.FirstTrack == 0 || t.Number < b.FirstTrack {
+			b.FirstTrack = t.Number
+		}
+		b.LastTrack = max(b.LastTrack, t.Number)
+	}
+	return b
+}
@@ -0,0 +1,150 @@
+package cdtext
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/theurs/gocue"
+)
+
+// utf16Encode и utf16Decode заменяют в тестах двухбайтовую кодировку MS-JIS.
+func utf16Encode(_ Charset, s string) ([]byte, error) {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b, nil
+}
+
+func utf16Decode(_ Charset, b []byte) (string, error) {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(u)), nil
+}
+
+const encodeTestCue = `
+TITLE "Kind of Blue"
+PERFORMER "Miles Davis"
+CATALOG 0074643135527
+REM GENRE Jazz
+DISC_ID 3A0A5E05
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    ISRC US-SM1-59-00113
+    COMPOSER "Miles Davis"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Freddie Freeloader"
+    PERFORMER "Miles Davis"
+    INDEX 01 09:22:00
+  TRACK 03 AUDIO
+    TITLE "Blue in Green"
+    PERFORMER "Miles Davis"
+    INDEX 01 15:08:00
+`
+
+// TestEncode_RoundTrip checks that encoded packs decode to the same values.
+func TestEncode_RoundTrip(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(encodeTestCue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	en := NewBlock(sheet, LanguageEnglish, CharsetISO8859_1)
+	ja := NewBlock(sheet, LanguageJapanese, CharsetMSJIS)
+	ja.Disc.Title = "カインド・オブ・ブルー"
+	ja.Tracks[1] = Fields{Title: "ソー・ホワット"}
+
+	data, warnings, err := (&Encoder{EncodeText: utf16Encode}).Encode(&CDText{Blocks: []*Block{en, ja}})
+	if err != nil {
+		t.Fatalf("Encode() returned an unexpected error: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Encode() returned unexpected warnings: %v", warnings)
+	}
+	if len(data)%PackSize != 0 {
+		t.Fatalf("got %d bytes, want whole packs", len(data))
+	}
+
+	cdt, err := (&Parser{Decode: utf16Decode}).Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	got := cdt.Block(LanguageEnglish)
+	if got == nil || got.Charset != CharsetISO8859_1 || got.FirstTrack != 1 || got.LastTrack != 3 {
+		t.Fatalf("English block got %+v", got)
+	}
+	if got.Disc != en.Disc || got.DiscID != "3A0A5E05" || got.Genre != GenreJazz {
+		t.Errorf("English disc got %+v %q %v, want %+v", got.Disc, got.DiscID, got.Genre, en.Disc)
+	}
+	for n := 1; n <= 3; n++ {
+		if got.Track(n) != en.Track(n) {
+			t.Errorf("English track %d got %+v, want %+v", n, got.Track(n), en.Track(n))
+		}
+	}
+	if got.Track(1).Code != "USSM15900113" || got.Track(3).Performer != "Miles Davis" {
+		t.Errorf("English track fields got %+v / %+v", got.Track(1), got.Track(3))
+	}
+	// Пакеты размеров: по одному пакету названий на каждые 12 байт и три пакета 0x8F.
+	if got.PackCounts[PackSizeInfo-0x80] != 3 || got.PackCounts[PackTitle-0x80] == 0 {
+		t.Errorf("pack counts got %v", got.PackCounts)
+	}
+
+	jp := cdt.Block(LanguageJapanese)
+	if jp == nil || jp.Charset != CharsetMSJIS || jp.Disc.Title != ja.Disc.Title || jp.Track(1).Title != "ソー・ホワット" {
+		t.Errorf("Japanese block got %+v", jp)
+	}
+}
+
+// TestEncode_Warnings checks warnings for text that does not fit the charset or the field size.
+func TestEncode_Warnings(t *testing.T) {
+	b := &Block{Charset: CharsetASCII, Disc: Fields{Title: "Café", Performer: strings.Repeat("x", 200)}}
+	data, warnings, err := Encode(&CDText{Blocks: []*Block{b}})
+	if err != nil {
+		t.Fatalf("Encode() returned an unexpected error: %v", err)
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("got warnings %v, want 2", warnings)
+	}
+	cdt, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title := cdt.Blocks[0].Disc.Title; title != "Caf?" {
+		t.Errorf("title got %q, want %q", title, "Caf?")
+	}
+
+	if _, _, err := Encode(&CDText{Blocks: []*Block{{Charset: CharsetMSJIS, Disc: Fields{Title: "x"}}}}); err == nil {
+		t.Error("Encode() accepted MS-JIS text without an encoder")
+	}
+}
+
+// TestEncode_Capacity checks that optional fields are dropped before failing.
+func TestEncode_Capacity(t *testing.T) {
+	b := &Block{Tracks: make(map[int]Fields)}
+	for n := 1; n <= 20; n++ {
+		b.Tracks[n] = Fields{Title: fmt.Sprintf("Track %d", n), Message: fmt.Sprint(n, strings.Repeat("m", 150))}
+	}
+	data, warnings, err := Encode(&CDText{Blocks: []*Block{b}})
+	if err != nil {
+		t.Fatalf("Encode() returned an unexpected error: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Type != PackMessage {
+		t.Errorf("got warnings %v, want MESSAGE to be dropped", warnings)
+	}
+	if n := len(data) / PackSize; n > maxPacks {
+		t.Errorf("got %d packs, want at most %d", n, maxPacks)
+	}
+
+	for n := 1; n <= 99; n++ {
+		b.Tracks[n] = Fields{Title: fmt.Sprint(n, strings.Repeat("t", 150))}
+	}
+	if _, _, err := Encode(&CDText{Blocks: []*Block{b}}); !errors.Is(err, ErrCapacity) {
+		t.Errorf("Encode() got %v, want ErrCapacity", err)
+	}
+}
@@ -0,0 +1,55 @@
+package cdtext
+
+import "fmt"
+
+// Genre — код жанра из пакета GENRE (0x87).
+type Genre uint16
+
+// Коды жанров по спецификации CD-TEXT.
+const (
+	GenreUnused Genre = iota
+	GenreNotDefined
+	GenreAdultContemporary
+	GenreAlternativeRock
+	GenreChildrensMusic
+	GenreClassical
+	GenreContemporaryChristian
+	GenreCountry
+	GenreDance
+	GenreEasyListening
+	GenreErotic
+	GenreFolk
+	GenreGospel
+	GenreHipHop
+	GenreJazz
+	GenreLatin
+	GenreMusical
+	GenreNewAge
+	GenreOpera
+	GenreOperetta
+	GenrePop
+	GenreRap
+	GenreReggae
+	GenreRock
+	GenreRhythmAndBlues
+	GenreSoundEffects
+	GenreSpokenWord
+	GenreWorldMusic
+)
+
+// genreNames — названия жанров в порядке кодов.
+var genreNames = []string{
+	"", "Not Defined", "Adult Contemporary", "Alternative Rock", "Childrens Music",
+	"Classical", "Contemporary Christian", "Country", "Dance", "Easy Listening",
+	"Erotic", "Folk", "Gospel", "Hip Hop", "Jazz", "Latin", "Musical", "New Age",
+	"Opera", "Operetta", "Pop Music", "Rap", "Reggae", "Rock Music",
+	"Rhythm & Blues", "Sound Effects", "Spoken Word", "World Music",
+}
+
+// String возвращает название жанра; для GenreUnused — пустую строку.
+func (g Genre) String() string {
+	if int(g) < len(genreNames) {
+		return genreNames[g]
+	}
+	return fmt.Sprintf("genre %d", uint16(g))
+}
@@ -0,0 +1,79 @@
+package cdtext
+
+import (
+	"errors"
+	"path/filepath"
+
+	"github.com/theurs/gocue"
+)
+
+// Merge дополняет CUE sheet c значениями первого блока CD-TEXT
+// (см. Block.Merge). Если блоков нет, CUE sheet не изменяется.
+func (t *CDText) Merge(c *gocue.Cuesheet) {
+	if len(t.Blocks) > 0 {
+		t.Blocks[0].Merge(c)
+	}
+}
+
+// Merge дополняет CUE sheet c значениями блока там, где в CUE их нет:
+// TITLE, PERFORMER, SONGWRITER, COMPOSER, ARRANGER и MESSAGE диска и треков,
+// CATALOG из UPC/EAN, ISRC треков, GENRE и DISC_ID. Жанр записывается
+// описанием, а если его нет — названием стандартного жанра; REM GENRE
+// в CUE тоже считается заданным жанром.
+func (b *Block) Merge(c *gocue.Cuesheet) {
+	fillFields(&c.Title, &c.Performer, &c.Songwriter, &c.Composer, &c.Arranger, &c.Message, b.Disc)
+	fill(&c.Catalog, b.Disc.Code)
+	genre := b.GenreText
+	if genre == "" {
+		genre = b.Genre.String()
+	}
+	if _, ok := c.RemValue("GENRE"); !ok {
+		fill(&c.Genre, genre)
+	}
+	fill(&c.DiscID, b.DiscID)
+
+	for _, t := range c.Tracks() {
+		f, ok := b.Tracks[t.Number]
+		if !ok {
+			continue
+		}
+		fillFields(&t.Title, &t.Performer, &t.Songwriter, &t.Composer, &t.Arranger, &t.Message, f)
+		fill(&t.ISRC, f.Code)
+	}
+}
+
+// MergeFile читает файл CD-TEXT, указанный в команде CDTEXTFILE CUE sheet c,
+// и дополняет c его значениями. Относительный путь отсчитывается от каталога
+// dir — обычно это каталог CUE-файла.
+func MergeFile(c *gocue.Cuesheet, dir string) error {
+	if c.CDTextFile == "" {
+		return errors.New("cuesheet has no CDTEXTFILE")
+	}
+	path := c.CDTextFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	t, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	t.Merge(c)
+	return nil
+}
+
+// fill записывает value в *dst, если там пусто.
+func fill(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// fillFields заполняет пустые текстовые поля диска или трека значениями f.
+func fillFields(title, performer, songwriter, composer, arranger, message *string, f Fields) {
+	fill(title, f.Title)
+	fill(performer, f.Performer)
+	fill(songwriter, f.Songwriter)
+	fill(composer, f.Composer)
+	fill(arranger, f.Arranger)
+	fill(message, f.Message)
+}
@@ -5,6 +5,7 @@ package gocue
 
 import (
 	"fmt"
+	"slices"
 	"time"
 )
 
@@ -44,29 +45,60 @@ func (t Timecode) AsDuration() time.Duration {
 // Index представляет команду INDEX в CUE-файле.
 // Каждый трек должен иметь как минимум INDEX 01.
 type Index struct {
-	Number int      // Номер индекса (00-99).
-	Time   Timecode // Временная метка индекса.
+	Number int      `json:"number"` // Номер индекса (00-99).
+	Time   Timecode `json:"time"`   // Временная метка индекса.
+
+	// Precise — точная позиция из нестандартного таймкода с долями секунды
+	// (например, "03:21.533"), который принимается только в нестрогом режиме.
+	// В этом случае Time содержит позицию, округленную вниз до фрейма.
+	// Нулевое значение означает, что точная позиция совпадает с Time.
+	Precise time.Duration `json:"precise_ns,omitzero"`
+
+	// File — файл, в котором лежит индекс, если это не файл самого трека.
+	// Так бывает в раскладке, где пауза (INDEX 00) дописана в конец предыдущего
+	// файла, а INDEX 01 находится уже в файле трека. Значение nil означает
+	// файл трека; используйте Track.IndexFile, чтобы получить файл в любом случае.
+	File *File `json:"-"`
 }
 
 // Track представляет один трек (дорожку) на диске.
 // Он содержит метаданные и временные метки.
 type Track struct {
-	Number     int
-	Type       string
-	Title      string
-	Performer  string
-	Songwriter string
-	ISRC       string   // International Standard Recording Code.
-	Flags      []string // Флаги субкодов (DCP, 4CH, PRE, SCMS).
-	Indices    []Index  // Список всех индексов трека.
-	Pregap     Timecode // Длительность предтрековой паузы.
-	Postgap    Timecode // Длительность посттрековой паузы.
+	Number     int      `json:"number"`
+	Type       string   `json:"type"`
+	Title      string   `json:"title,omitempty"`
+	Performer  string   `json:"performer,omitempty"`
+	Songwriter string   `json:"songwriter,omitempty"`
+	Composer   string   `json:"composer,omitempty"`
+	Arranger   string   `json:"arranger,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	ISRC       string   `json:"isrc,omitempty"`   // International Standard Recording Code.
+	Flags      []string `json:"flags,omitempty"`  // Флаги субкодов (DCP, 4CH, PRE, SCMS).
+	Indices    []Index  `json:"indices"`          // Список всех индексов трека.
+	Pregap     Timecode `json:"pregap,omitzero"`  // Длительность предтрековой паузы.
+	Postgap    Timecode `json:"postgap,omitzero"` // Длительность посттрековой паузы.
+	Rem        []string `json:"rem,omitempty"`    // Комментарии (REM) внутри блока трека.
+
+	// Extensions — нераспознанные команды внутри блока трека в порядке следования.
+	Extensions []Command `json:"extensions,omitempty"`
+	// Ext — значения, сохраненные обработчиками команд (см. Parser.Handle).
+	Ext map[string]any `json:"ext,omitempty"`
 
 	// parentFile - внутренняя ссылка на родительский файл для вычислений.
 	parentFile *File
 }
 
+// IndexFile возвращает файл, к которому относится позиция индекса трека:
+// idx.File, если он задан, и файл самого трека в противном случае.
+func (t *Track) IndexFile(idx Index) *File {
+	if idx.File != nil {
+		return idx.File
+	}
+	return t.parentFile
+}
+
 // StartTime возвращает официальное время начала трека (время, указанное в INDEX 01).
+// Время отсчитывается от начала файла, в котором лежит INDEX 01.
 // Если INDEX 01 не найден, возвращает нулевой таймкод.
 func (t *Track) StartTime() Timecode {
 	for _, idx := range t.Indices {
@@ -77,6 +109,14 @@ func (t *Track) StartTime() Timecode {
 	return Timecode{}
 }
 
+// startFile возвращает файл, в котором лежит INDEX 01 трека.
+func (t *Track) startFile() *File {
+	if idx, ok := t.Index(1); ok {
+		return t.IndexFile(idx)
+	}
+	return t.parentFile
+}
+
 // Duration вычисляет длительность трека.
 // Для последнего трека на диске длительность определить невозможно,
 // поэтому будет возвращена нулевая длительность. В этом случае потребитель
@@ -85,61 +125,36 @@ func (t *Track) Duration() time.Duration {
 	if t.parentFile == nil {
 		return 0
 	}
-	return t.parentFile.getTrackDuration(t.Number)
+	return t.parentFile.getTrackDuration(t)
 }
 
 // File представляет команду FILE в CUE-файле.
 // Он описывает один физический файл (например, .wav или .bin) и треки внутри него.
 type File struct {
-	Name   string
-	Type   string   // Тип файла (WAVE, MP3, BINARY и т.д.).
-	Tracks []*Track // Список треков, содержащихся в этом файле.
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`   // Тип файла (WAVE, MP3, BINARY и т.д.).
+	Tracks []*Track `json:"tracks"` // Список треков, содержащихся в этом файле.
+
+	// Extensions — нераспознанные команды между FILE и первым TRACK.
+	Extensions []Command `json:"extensions,omitempty"`
+	// Ext — значения, сохраненные обработчиками команд (см. Parser.Handle).
+	Ext map[string]any `json:"ext,omitempty"`
 
 	// parentSheet - внутренняя ссылка на корневой объект.
 	parentSheet *Cuesheet
 }
 
-// getTrackDuration ищет текущий и следующий трек для вычисления длительности.
+// getTrackDuration ищет следующий за track трек для вычисления длительности.
 // Эта логика вынесена на уровень File, так как трек сам по себе не знает о соседях.
-func (f *File) getTrackDuration(trackNumber int) time.Duration {
-	var currentTrack, nextTrack *Track
-
-	// Ищем все треки по порядку во всем CUE sheet
-	var allTracks []*Track
-	if f.parentSheet != nil {
-		for _, file := range f.parentSheet.Files {
-			allTracks = append(allTracks, file.Tracks...)
-		}
-	} else {
-		// Fallback, если родительский sheet не установлен
-		allTracks = f.Tracks
-	}
-
-	for i, tr := range allTracks {
-		if tr.Number == trackNumber {
-			currentTrack = tr
-			// Ищем следующий трек
-			if i+1 < len(allTracks) && allTracks[i+1].Number == trackNumber+1 {
-				nextTrack = allTracks[i+1]
-			}
-			break
-		}
+func (f *File) getTrackDuration(track *Track) time.Duration {
+	nextTrack := f.findNextTrack(track)
+	if nextTrack == nil {
+		return 0 // Трек не найден, он последний или следующий трек в другом файле
 	}
 
-	if currentTrack == nil || nextTrack == nil {
-		return 0 // Трек не найден или он последний
-	}
-
-	startTime := currentTrack.StartTime().AsDuration()
+	startTime := track.StartTime().AsDuration()
 	nextStartTime := nextTrack.StartTime().AsDuration()
 
-	// Если следующий трек в другом файле, его время начинается с нуля,
-	// и мы не можем вычислить общую длительность без знания длительности аудиофайла.
-	// Это ограничение формата CUE.
-	if currentTrack.parentFile != nextTrack.parentFile {
-		return 0
-	}
-
 	if nextStartTime < startTime {
 		return 0 // Некорректные данные в CUE.
 	}
@@ -147,16 +162,74 @@ func (f *File) getTrackDuration(trackNumber int) time.Duration {
 	return nextStartTime - startTime
 }
 
+// findNextTrack ищет трек, следующий за track.
+// Следующий трек возвращается только если он лежит в том же файле: если он
+// в другом файле, его время начинается с нуля, и мы не можем вычислить общую
+// длительность без знания длительности аудиофайла. Это ограничение формата CUE.
+func (f *File) findNextTrack(track *Track) *Track {
+	// Ищем все треки по порядку во всем CUE sheet
+	tracks := slices.All(f.Tracks)
+	if f.parentSheet != nil {
+		tracks = f.parentSheet.Tracks()
+	} // Иначе fallback: родительский sheet не установлен
+
+	var prev, next *Track
+	for i, tr := range tracks {
+		// Трек 00 — виртуальный скрытый трек (см. Cuesheet.HiddenTrack):
+		// его нет в списке, а следом за ним идет трек 01.
+		if (i == 0 && track.Number == 0) || prev == track {
+			if tr.Number == track.Number+1 {
+				next = tr
+			}
+			break
+		}
+		prev = tr
+	}
+
+	if next != nil && track.startFile() != next.startFile() {
+		return nil
+	}
+	return next
+}
+
 // Cuesheet — это корневая структура, представляющая весь CUE-файл.
 // Она содержит глобальные метаданные и список файлов.
 type Cuesheet struct {
-	Title      string
-	Performer  string
-	Songwriter string
-	Catalog    string   // Media Catalog Number (MCN).
-	Files      []*File  // Список файлов, связанных с этим CUE sheet.
-	Rem        []string // Список всех комментариев (REM).
-	CDTextFile string   // Путь к внешнему файлу CD-TEXT.
+	Title      string   `json:"title,omitempty"`
+	Performer  string   `json:"performer,omitempty"`
+	Songwriter string   `json:"songwriter,omitempty"`
+	Catalog    string   `json:"catalog,omitempty"`      // Media Catalog Number (MCN).
+	Files      []*File  `json:"files"`                  // Список файлов, связанных с этим CUE sheet.
+	Rem        []string `json:"rem,omitempty"`          // Комментарии (REM) вне блоков TRACK (см. AllRem).
+	CDTextFile string   `json:"cd_text_file,omitempty"` // Путь к внешнему файлу CD-TEXT.
+
+	// Расширенные команды CD-TEXT, которые понимают ImgBurn и другие
+	// CDRWIN-совместимые программы записи.
+	Composer string `json:"composer,omitempty"`
+	Arranger string `json:"arranger,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Genre    string `json:"genre,omitempty"`
+	DiscID   string `json:"disc_id,omitempty"`   // DISC_ID.
+	UPCEAN   string `json:"upc_ean,omitempty"`   // UPC_EAN.
+	TOCInfo  string `json:"toc_info,omitempty"`  // TOC_INFO.
+	TOCInfo2 string `json:"toc_info2,omitempty"` // TOC_INFO2.
+	SizeInfo string `json:"size_info,omitempty"` // SIZE_INFO.
+
+	// Extensions — нераспознанные команды вне блоков FILE в порядке следования.
+	Extensions []Command `json:"extensions,omitempty"`
+	// Ext — значения, сохраненные обработчиками команд (см. Parser.Handle).
+	Ext map[string]any `json:"ext,omitempty"`
+
+	// LineEnding — стиль перевода строки исходного файла, который сохраняет
+	// Write. Пустое значение означает LineEndingLF.
+	LineEnding LineEnding `json:"line_ending,omitempty"`
+}
+
+// Command — команда CUE, которую парсер не распознал. Такие команды
+// сохраняются, чтобы не потерять их при повторной записи CUE sheet.
+type Command struct {
+	Name string   `json:"name"` // Имя команды в верхнем регистре.
+	Args []string `json:"args,omitempty"`
 }
 
 // NewTimecodeFromFrames создает объект Timecode из общего количества фреймов.
@@ -0,0 +1,275 @@
+package gocue
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrStop возвращается обработчиком событий, чтобы остановить Decoder.Decode
+// без ошибки.
+var ErrStop = errors.New("stop decoding")
+
+// EventKind — тип события Decoder.
+type EventKind int
+
+const (
+	// EventMeta — команда метаданных: CATALOG, CDTEXTFILE, TITLE, PERFORMER,
+	// SONGWRITER, команды CD-TEXT, ISRC, FLAGS, PREGAP или POSTGAP.
+	EventMeta       EventKind = iota + 1
+	EventRem                  // Комментарий REM.
+	EventFileStart            // Команда FILE.
+	EventTrackStart           // Команда TRACK.
+	EventIndex                // Команда INDEX.
+	// EventUnknown — команда, которую декодер не разбирает: неизвестная или
+	// переданная обработчику Parser.Handle.
+	EventUnknown
+)
+
+// String возвращает название типа события.
+func (k EventKind) String() string {
+	switch k {
+	case EventMeta:
+		return "Meta"
+	case EventRem:
+		return "Rem"
+	case EventFileStart:
+		return "FileStart"
+	case EventTrackStart:
+		return "TrackStart"
+	case EventIndex:
+		return "Index"
+	case EventUnknown:
+		return "Unknown"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Event — одна команда CUE. Декодер проверяет только синтаксис команды;
+// к какому диску, файлу или треку она относится, определяется порядком
+// событий: метаданные после EventTrackStart относятся к треку.
+type Event struct {
+	Kind    EventKind
+	Line    int      // Номер строки, начиная с 1.
+	Command string   // Имя команды в верхнем регистре.
+	Args    []string // Аргументы без кавычек.
+	Text    string   // Строка целиком, без пробелов по краям.
+
+	Value  string   // EventMeta и EventRem: значение команды.
+	Name   string   // EventFileStart: имя файла.
+	Type   string   // EventFileStart и EventTrackStart: тип в верхнем регистре.
+	Number int      // EventTrackStart: номер трека.
+	Index  Index    // EventIndex: номер и позиция индекса.
+	Time   Timecode // EventMeta для PREGAP и POSTGAP: длительность паузы.
+}
+
+// Handler получает события от Decoder.Decode. Если HandleEvent возвращает
+// ErrStop, декодирование останавливается без ошибки.
+type Handler interface {
+	HandleEvent(ev *Event) error
+}
+
+// DefaultMaxLineLength — ограничение длины строки CUE по умолчанию.
+const DefaultMaxLineLength = 64 * 1024
+
+// ErrLineTooLong возвращается для строки длиннее MaxLineLength.
+var ErrLineTooLong = errors.New("line too long")
+
+// Decoder построчно читает CUE sheet и выдает его команды событиями,
+// не строя Cuesheet. Он подходит для быстрого извлечения нескольких полей:
+// чтение можно прекратить в любой момент, например после заголовка альбома.
+//
+// Декодер работает с байтами строки и выделяет память только под ее текст:
+// аргументы событий — подстроки Event.Text. Срез Event.Args переиспользуется
+// и действителен только до следующего вызова Next.
+type Decoder struct {
+	// Lenient разрешает таймкоды INDEX с долями секунды, незакрытые кавычки
+	// и имя команды в кавычках (см. Parser.Lenient).
+	Lenient bool
+	// MaxLineLength — максимальная длина строки в байтах без перевода строки;
+	// 0 означает DefaultMaxLineLength. Более длинная строка дает ErrLineTooLong.
+	MaxLineLength int
+
+	// OnWarning, если задан, получает предупреждения о строках, которые
+	// пришлось исправить: удаленные BOM и управляющие символы, смена стиля
+	// перевода строки.
+	OnWarning func(Warning)
+
+	src    io.Reader
+	buf    []byte   // Буфер чтения; строки — его срезы.
+	start  int      // Начало непрочитанных данных в buf.
+	end    int      // Конец данных в buf.
+	err    error    // Ошибка чтения src, в том числе io.EOF.
+	clean  []byte   // Буфер строки после удаления лишних символов.
+	args   []string // Переиспользуемый срез частей строки.
+	line   int
+	custom map[string]CommandFunc // Команды, которые выдаются как EventUnknown.
+
+	endings [3]int     // Число строк с LF, CRLF и CR.
+	first   LineEnding // Стиль первой строки с переводом строки.
+}
+
+// NewDecoder создает декодер, читающий из r.
+func NewDecoder(r io.Reader) *Decoder {
+	d := &Decoder{}
+	d.Reset(r)
+	return d
+}
+
+// Reset переключает декодер на чтение из r, сохраняя настройки и буферы.
+// Так один декодер можно использовать для множества файлов.
+func (d *Decoder) Reset(r io.Reader) {
+	d.src, d.err = r, nil
+	d.start, d.end = 0, 0
+	d.line = 0
+	d.endings, d.first = [3]int{}, ""
+}
+
+// Next возвращает следующее событие. Пустые строки пропускаются. В конце
+// данных возвращается io.EOF; ошибки содержат номер строки.
+func (d *Decoder) Next() (Event, error) {
+	for {
+		raw, ending, err := d.readLine()
+		if err == io.EOF {
+			return Event{}, io.EOF
+		}
+		d.line++
+		if err != nil {
+			if errors.Is(err, ErrLineTooLong) {
+				return Event{}, fmt.Errorf("line %d: %w", d.line, err)
+			}
+			return Event{}, fmt.Errorf("error reading input: %w", err)
+		}
+		d.countEnding(ending)
+		raw = bytes.TrimSpace(d.sanitize(raw))
+		if len(raw) == 0 {
+			continue // Пропускаем пустые строки
+		}
+
+		line := string(raw)
+		parts, err := smartSplit(line, d.args[:0], d.Lenient)
+		if err == nil && line[0] == '"' && !d.Lenient {
+			err = errors.New("command cannot be quoted")
+		}
+		if err != nil {
+			return Event{}, fmt.Errorf("line %d: invalid quoting: %w", d.line, err)
+		}
+		d.args = parts
+
+		ev := Event{Line: d.line, Command: strings.ToUpper(parts[0]), Args: parts[1:], Text: line}
+		if err := d.decode(&ev); err != nil {
+			return Event{}, fmt.Errorf("line %d: %w", d.line, err)
+		}
+		return ev, nil
+	}
+}
+
+// Decode передает все события обработчику h. Ошибка обработчика прерывает
+// чтение и возвращается как есть, кроме ErrStop.
+func (d *Decoder) Decode(h Handler) error {
+	for {
+		ev, err := d.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.HandleEvent(&ev); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// decode определяет тип события и разбирает аргументы команды.
+func (d *Decoder) decode(ev *Event) error {
+	args := ev.Args
+	if _, ok := d.custom[ev.Command]; ok {
+		ev.Kind = EventUnknown
+		return nil
+	}
+
+	switch ev.Command {
+	case "REM":
+		ev.Kind, ev.Value = EventRem, strings.Join(args, " ")
+	case "CATALOG", "CDTEXTFILE", "TITLE", "PERFORMER", "SONGWRITER", "COMPOSER", "ARRANGER", "MESSAGE", "ISRC":
+		if len(args) < 1 {
+			return fmt.Errorf("%s command requires an argument", ev.Command)
+		}
+		ev.Kind, ev.Value = EventMeta, args[0]
+	case "GENRE", "DISC_ID", "UPC_EAN", "TOC_INFO", "TOC_INFO2", "SIZE_INFO":
+		if len(args) < 1 {
+			return fmt.Errorf("%s command requires an argument", ev.Command)
+		}
+		ev.Kind, ev.Value = EventMeta, strings.Join(args, " ")
+	case "FLAGS":
+		ev.Kind, ev.Value = EventMeta, strings.Join(args, " ")
+	case "PREGAP", "POSTGAP":
+		if len(args) < 1 {
+			return fmt.Errorf("%s command requires a timecode argument", ev.Command)
+		}
+		timecode, err := ParseTimecode(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid timecode for %s: %w", ev.Command, err)
+		}
+		ev.Kind, ev.Value, ev.Time = EventMeta, args[0], timecode
+	case "FILE":
+		if len(args) < 2 {
+			return errors.New("FILE command requires name and type arguments")
+		}
+		ev.Kind, ev.Name, ev.Type = EventFileStart, args[0], strings.ToUpper(args[1])
+	case "TRACK":
+		if len(args) < 2 {
+			return errors.New("TRACK command requires number and type arguments")
+		}
+		num, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid track number: %w", err)
+		}
+		ev.Kind, ev.Number, ev.Type = EventTrackStart, num, strings.ToUpper(args[1])
+	case "INDEX":
+		if len(args) < 2 {
+			return errors.New("INDEX command requires number and timecode arguments")
+		}
+		num, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid index number: %w", err)
+		}
+		index, err := d.parseIndexTime(num, args[1])
+		if err != nil {
+			return fmt.Errorf("invalid timecode for INDEX: %w", err)
+		}
+		ev.Kind, ev.Index = EventIndex, index
+	default:
+		ev.Kind = EventUnknown
+	}
+	return nil
+}
+
+// parseIndexTime разбирает таймкод команды INDEX. В нестрогом режиме
+// дополнительно принимаются таймкоды с долями секунды.
+func (d *Decoder) parseIndexTime(number int, s string) (Index, error) {
+	timecode, err := ParseTimecode(s)
+	if err == nil {
+		return Index{Number: number, Time: timecode}, nil
+	}
+	if !d.Lenient || !strings.Contains(s, ".") {
+		return Index{}, err
+	}
+
+	precise, perr := parsePreciseTimecode(s)
+	if perr != nil {
+		return Index{}, perr
+	}
+	return Index{
+		Number:  number,
+		Time:    TimecodeFromDuration(precise, RoundFloor),
+		Precise: precise,
+	}, nil
+}
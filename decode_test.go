@@ -0,0 +1,126 @@
+package gocue
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const decodeTestCue = `
+REM GENRE Jazz
+PERFORMER "Miles Davis"
+TITLE "Kind of Blue"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    PREGAP 00:02:00
+    INDEX 01 00:00:00
+  X_VENDOR 1
+`
+
+// TestDecoder_Next checks the event kinds, typed values and line numbers.
+func TestDecoder_Next(t *testing.T) {
+	want := []Event{
+		{Kind: EventRem, Line: 2, Command: "REM", Value: "GENRE Jazz"},
+		{Kind: EventMeta, Line: 3, Command: "PERFORMER", Value: "Miles Davis"},
+		{Kind: EventMeta, Line: 4, Command: "TITLE", Value: "Kind of Blue"},
+		{Kind: EventFileStart, Line: 5, Command: "FILE", Name: "a.wav", Type: "WAVE"},
+		{Kind: EventTrackStart, Line: 6, Command: "TRACK", Number: 1, Type: "AUDIO"},
+		{Kind: EventMeta, Line: 7, Command: "TITLE", Value: "So What"},
+		{Kind: EventMeta, Line: 8, Command: "PREGAP", Value: "00:02:00", Time: Timecode{Seconds: 2}},
+		{Kind: EventIndex, Line: 9, Command: "INDEX", Index: Index{Number: 1}},
+		{Kind: EventUnknown, Line: 10, Command: "X_VENDOR"},
+	}
+
+	d := NewDecoder(strings.NewReader(decodeTestCue))
+	for i, w := range want {
+		ev, err := d.Next()
+		if err != nil {
+			t.Fatalf("event %d: Next() returned an unexpected error: %v", i, err)
+		}
+		// Аргументы и текст строки здесь не сравниваются.
+		ev.Args, ev.Text = nil, ""
+		if !reflect.DeepEqual(ev, w) {
+			t.Errorf("event %d got %+v, want %+v", i, ev, w)
+		}
+	}
+	if _, err := d.Next(); err != io.EOF {
+		t.Errorf("Next() at the end got %v, want io.EOF", err)
+	}
+}
+
+// stopAfterHeader collects album metadata and stops at the first FILE.
+type stopAfterHeader struct {
+	meta map[string]string
+}
+
+func (h *stopAfterHeader) HandleEvent(ev *Event) error {
+	switch ev.Kind {
+	case EventFileStart:
+		return ErrStop
+	case EventMeta:
+		h.meta[ev.Command] = ev.Value
+	}
+	return nil
+}
+
+// TestDecoder_Decode checks early stopping and error reporting.
+func TestDecoder_Decode(t *testing.T) {
+	// Ошибка после первого FILE не должна помешать: чтение к ней не доходит.
+	h := &stopAfterHeader{meta: map[string]string{}}
+	if err := NewDecoder(strings.NewReader(decodeTestCue + "INDEX xx\n")).Decode(h); err != nil {
+		t.Fatalf("Decode() returned an unexpected error: %v", err)
+	}
+	if len(h.meta) != 2 || h.meta["TITLE"] != "Kind of Blue" || h.meta["PERFORMER"] != "Miles Davis" {
+		t.Errorf("got header %v", h.meta)
+	}
+
+	err := NewDecoder(strings.NewReader("TITLE \"a\"\nPREGAP 00:99:00\n")).Decode(h)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: invalid timecode for PREGAP") {
+		t.Errorf("got error %v", err)
+	}
+
+	errBoom := errors.New("boom")
+	err = NewDecoder(strings.NewReader("TITLE \"a\"\n")).Decode(handlerFunc(func(*Event) error { return errBoom }))
+	if !errors.Is(err, errBoom) {
+		t.Errorf("got error %v, want the handler error", err)
+	}
+}
+
+type handlerFunc func(*Event) error
+
+func (f handlerFunc) HandleEvent(ev *Event) error { return f(ev) }
+
+// TestDecoder_MaxLineLength checks lines longer than the read buffer and the limit.
+func TestDecoder_MaxLineLength(t *testing.T) {
+	title := strings.Repeat("x", 10000)
+	cue := "TITLE \"" + title + "\"\r\nPERFORMER \"a\"\n"
+
+	d := NewDecoder(strings.NewReader(cue))
+	ev, err := d.Next()
+	if err != nil || ev.Value != title {
+		t.Fatalf("Next() got %d bytes, %v; want the long title", len(ev.Value), err)
+	}
+	if ev, err := d.Next(); err != nil || ev.Value != "a" {
+		t.Fatalf("Next() after a long line got %+v, %v", ev, err)
+	}
+
+	d = NewDecoder(strings.NewReader(cue))
+	d.MaxLineLength = 9000
+	_, err = d.Next()
+	if !errors.Is(err, ErrLineTooLong) || !strings.HasPrefix(err.Error(), "line 1: ") {
+		t.Errorf("got error %v, want ErrLineTooLong on line 1", err)
+	}
+
+	// Строка ровно в MaxLineLength байт допустима.
+	d.Reset(strings.NewReader(cue))
+	d.MaxLineLength = len(title) + len(`TITLE ""`)
+	if _, err := d.Next(); err != nil {
+		t.Errorf("line at the limit got error %v", err)
+	}
+	if _, err := (&Parser{MaxLineLength: 100}).Parse(strings.NewReader(cue)); !errors.Is(err, ErrLineTooLong) {
+		t.Errorf("Parser.Parse() got error %v, want ErrLineTooLong", err)
+	}
+}
@@ -0,0 +1,329 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrTrackNotFound возвращается, если трек с указанным номером отсутствует в CUE sheet.
+var ErrTrackNotFound = errors.New("track not found")
+
+// Clone возвращает глубокую копию CUE sheet. Копия не разделяет с оригиналом
+// ни срезы, ни указатели, а ее внутренние ссылки указывают на новые элементы.
+// Карты Ext копируются, но сами значения в них — нет.
+func (c *Cuesheet) Clone() *Cuesheet {
+	clone := *c
+	clone.Rem = slices.Clone(c.Rem)
+	clone.Extensions = cloneCommands(c.Extensions)
+	clone.Ext = maps.Clone(c.Ext)
+	clone.Files = make([]*File, len(c.Files))
+	files := make(map[*File]*File, len(c.Files)) // Старый файл -> его копия.
+	for i, f := range c.Files {
+		file := *f
+		file.Extensions = cloneCommands(f.Extensions)
+		file.Ext = maps.Clone(f.Ext)
+		clone.Files[i] = &file
+		files[f] = &file
+	}
+	for _, file := range clone.Files {
+		tracks := file.Tracks
+		file.Tracks = make([]*Track, len(tracks))
+		for j, t := range tracks {
+			track := *t
+			track.Flags = slices.Clone(t.Flags)
+			track.Rem = slices.Clone(t.Rem)
+			track.Extensions = cloneCommands(t.Extensions)
+			track.Ext = maps.Clone(t.Ext)
+			track.Indices = slices.Clone(t.Indices)
+			for k, idx := range track.Indices {
+				if idx.File != nil {
+					track.Indices[k].File = files[idx.File]
+				}
+			}
+			file.Tracks[j] = &track
+		}
+	}
+	clone.Relink()
+	return &clone
+}
+
+// cloneCommands возвращает глубокую копию списка команд.
+func cloneCommands(cmds []Command) []Command {
+	cmds = slices.Clone(cmds)
+	for i := range cmds {
+		cmds[i].Args = slices.Clone(cmds[i].Args)
+	}
+	return cmds
+}
+
+// RenumberTracks нумерует треки подряд в порядке их следования в CUE sheet,
+// начиная с номера первого трека (или с 1, если он меньше 1).
+func (c *Cuesheet) RenumberTracks() {
+	next := 1
+	first := true
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			if first {
+				next = max(t.Number, 1)
+				first = false
+			}
+			t.Number = next
+			next++
+		}
+	}
+}
+
+// InsertTrack вставляет трек в файл file, который должен принадлежать этому
+// CUE sheet. Позиция определяется по INDEX 01: трек встает между соседями,
+// после чего все треки перенумеровываются. Операция отклоняется, если у трека
+// нет INDEX 01, он совпадает с началом другого трека или попадает на
+// промежуток, уже размеченный индексами соседнего трека.
+func (c *Cuesheet) InsertTrack(file *File, track *Track) error {
+	if !slices.Contains(c.Files, file) {
+		return errors.New("file does not belong to this cuesheet")
+	}
+	if _, ok := track.Index(1); !ok {
+		return errors.New("inserted track must have INDEX 01")
+	}
+	if c.trackCount() >= 99 {
+		return errors.New("cuesheet already has 99 tracks")
+	}
+	if !indicesSorted(track.Indices) {
+		return errors.New("inserted track indices must be in ascending order")
+	}
+
+	start := track.StartTime()
+	pos := 0
+	for pos < len(file.Tracks) && file.Tracks[pos].StartTime().Before(start) {
+		pos++
+	}
+	if pos < len(file.Tracks) && file.Tracks[pos].StartTime() == start {
+		return fmt.Errorf("track %d already starts at %s", file.Tracks[pos].Number, start)
+	}
+	// Все индексы, лежащие в этом файле, должны остаться упорядоченными:
+	// индексы предшествующих треков — до нового трека, а индексы следующих,
+	// в том числе INDEX 00 трека из следующего FILE, — после него.
+	first, last := track.Indices[0].Time, track.Indices[len(track.Indices)-1].Time
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			before := slices.Contains(file.Tracks[:pos], t)
+			for _, idx := range t.Indices {
+				if t.IndexFile(idx) != file {
+					continue
+				}
+				if before && !idx.Time.Before(first) {
+					return fmt.Errorf("track %d has indices after %s", t.Number, first)
+				}
+				if !before && !last.Before(idx.Time) {
+					return fmt.Errorf("inserted track overlaps track %d", t.Number)
+				}
+			}
+		}
+	}
+
+	track.parentFile = file
+	file.Tracks = slices.Insert(file.Tracks, pos, track)
+	c.RenumberTracks()
+	return nil
+}
+
+// RemoveTrack удаляет трек с указанным номером и перенумеровывает оставшиеся.
+// Аудио удаленного трека в пределах файла становится частью предыдущего трека.
+// Файл, в котором не осталось ни треков, ни индексов других треков (например,
+// INDEX 00 следующего трека), удаляется из CUE sheet вместе с треком.
+// Удалить единственный трек CUE sheet нельзя.
+func (c *Cuesheet) RemoveTrack(number int) error {
+	file, pos := c.findTrack(number)
+	if file == nil {
+		return fmt.Errorf("track %d: %w", number, ErrTrackNotFound)
+	}
+	if c.trackCount() == 1 {
+		return errors.New("cannot remove the only track")
+	}
+
+	file.Tracks[pos].parentFile = nil
+	file.Tracks = slices.Delete(file.Tracks, pos, pos+1)
+	if len(file.Tracks) == 0 && !c.fileReferenced(file) {
+		c.Files = slices.DeleteFunc(c.Files, func(f *File) bool { return f == file })
+		file.parentSheet = nil
+	}
+	c.RenumberTracks()
+	return nil
+}
+
+// fileReferenced сообщает, лежит ли в file хотя бы один индекс трека
+// из другого файла.
+func (c *Cuesheet) fileReferenced(file *File) bool {
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			for _, idx := range t.Indices {
+				if idx.File == file {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// MergeTracks объединяет трек second с предшествующим ему треком first.
+// Треки должны идти подряд и лежать в одном файле. Объединенный трек сохраняет
+// метаданные first; INDEX 00 трека second отбрасывается (пауза становится частью
+// трека), а его INDEX 01 и последующие становятся очередными индексами first.
+// Операция отклоняется, если у second задан PREGAP или у first — POSTGAP:
+// тишину внутри трека формат CUE выразить не может.
+func (c *Cuesheet) MergeTracks(first, second int) error {
+	if second != first+1 {
+		return fmt.Errorf("tracks %d and %d are not adjacent", first, second)
+	}
+	file, pos := c.findTrack(first)
+	if file == nil {
+		return fmt.Errorf("track %d: %w", first, ErrTrackNotFound)
+	}
+	if pos+1 >= len(file.Tracks) || file.Tracks[pos+1].Number != second {
+		return fmt.Errorf("track %d does not follow track %d in the same file", second, first)
+	}
+
+	a, b := file.Tracks[pos], file.Tracks[pos+1]
+	if b.Pregap != (Timecode{}) {
+		return fmt.Errorf("track %d has a PREGAP that cannot be merged", second)
+	}
+	if a.Postgap != (Timecode{}) {
+		return fmt.Errorf("track %d has a POSTGAP that cannot be merged", first)
+	}
+	next := 2
+	if len(a.Indices) > 0 {
+		next = max(a.Indices[len(a.Indices)-1].Number+1, 2)
+	}
+	var moved []Index
+	for _, idx := range b.Indices {
+		if idx.Number == 0 {
+			continue
+		}
+		idx.Number = next
+		next++
+		moved = append(moved, idx)
+	}
+	if next-1 > 99 {
+		return errors.New("merged track would have more than 99 indices")
+	}
+
+	a.Indices = append(a.Indices, moved...)
+	a.Postgap = b.Postgap
+	b.parentFile = nil
+	file.Tracks = slices.Delete(file.Tracks, pos+1, pos+2)
+	c.RenumberTracks()
+	return nil
+}
+
+// SplitTrackAt делит трек с указанным номером на два в позиции at, заданной
+// относительно начала файла. Новый трек начинается с INDEX 01 в позиции at,
+// получает тип, исполнителя, авторов, аранжировщика и флаги исходного трека, а также
+// все его индексы после at. Если at совпадает с одним из индексов 02-99,
+// этот индекс становится INDEX 01 нового трека. Позиция должна лежать
+// строго внутри трека. После разделения треки перенумеровываются.
+func (c *Cuesheet) SplitTrackAt(number int, at Timecode) error {
+	file, pos := c.findTrack(number)
+	if file == nil {
+		return fmt.Errorf("track %d: %w", number, ErrTrackNotFound)
+	}
+	track := file.Tracks[pos]
+	if !at.After(track.StartTime()) {
+		return fmt.Errorf("split position %s is not after the start of track %d", at, number)
+	}
+	if next, ok := c.nextIndexIn(file, track); ok && !at.Before(next) {
+		return fmt.Errorf("split position %s is not inside track %d", at, number)
+	}
+	if c.trackCount() >= 99 {
+		return errors.New("cuesheet already has 99 tracks")
+	}
+
+	split := &Track{
+		Type:       track.Type,
+		Performer:  track.Performer,
+		Songwriter: track.Songwriter,
+		Composer:   track.Composer,
+		Arranger:   track.Arranger,
+		Flags:      slices.Clone(track.Flags),
+		Indices:    []Index{{Number: 1, Time: at}},
+		Postgap:    track.Postgap,
+		parentFile: file,
+	}
+	var kept []Index
+	for _, idx := range track.Indices {
+		switch {
+		case idx.Time.Before(at) || idx.Number <= 1:
+			kept = append(kept, idx)
+		case idx.Time == at:
+			split.Indices[0].Precise = idx.Precise
+		default:
+			idx.Number = len(split.Indices) + 1
+			split.Indices = append(split.Indices, idx)
+		}
+	}
+	track.Indices = kept
+	track.Postgap = Timecode{}
+
+	file.Tracks = slices.Insert(file.Tracks, pos+1, split)
+	c.RenumberTracks()
+	return nil
+}
+
+// nextIndexIn возвращает ближайшую позицию индекса другого трека, лежащего
+// в файле file после начала track. Учитываются и индексы треков из других
+// FILE, например INDEX 00 следующего трека в конце этого файла.
+func (c *Cuesheet) nextIndexIn(file *File, track *Track) (Timecode, bool) {
+	start := track.StartTime()
+	var next Timecode
+	found := false
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			if t == track {
+				continue
+			}
+			for _, idx := range t.Indices {
+				if t.IndexFile(idx) != file || !idx.Time.After(start) {
+					continue
+				}
+				if !found || idx.Time.Before(next) {
+					next, found = idx.Time, true
+				}
+			}
+		}
+	}
+	return next, found
+}
+
+// findTrack ищет трек с указанным номером и возвращает его файл и позицию в file.Tracks.
+// Если трек не найден, file будет равен nil.
+func (c *Cuesheet) findTrack(number int) (file *File, pos int) {
+	for _, f := range c.Files {
+		for i, t := range f.Tracks {
+			if t.Number == number {
+				return f, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+// trackCount возвращает общее количество треков во всех файлах.
+func (c *Cuesheet) trackCount() int {
+	n := 0
+	for _, f := range c.Files {
+		n += len(f.Tracks)
+	}
+	return n
+}
+
+// indicesSorted сообщает, что индексы идут по возрастанию и номеров, и времени.
+func indicesSorted(indices []Index) bool {
+	for i := 1; i < len(indices); i++ {
+		if indices[i].Number <= indices[i-1].Number || indices[i].Time.Before(indices[i-1].Time) {
+			return false
+		}
+	}
+	return true
+}
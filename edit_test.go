@@ -0,0 +1,274 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const editTestCue = `
+FILE "album.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 03:58:00
+    INDEX 01 04:00:00
+    INDEX 02 05:00:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    INDEX 01 08:00:00
+`
+
+// parseEditTestCue разбирает общий CUE sheet для тестов редактирования.
+func parseEditTestCue(t *testing.T) *Cuesheet {
+	t.Helper()
+	sheet, err := Parse(strings.NewReader(editTestCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	return sheet
+}
+
+// trackSummary описывает треки как "номер:название:INDEX 01" для сравнения в тестах.
+func trackSummary(sheet *Cuesheet) string {
+	var parts []string
+	for _, f := range sheet.Files {
+		for _, tr := range f.Tracks {
+			parts = append(parts, fmt.Sprintf("%02d:%s:%s", tr.Number, tr.Title, tr.StartTime()))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// TestCuesheet_Clone checks that a clone shares no state with the original.
+func TestCuesheet_Clone(t *testing.T) {
+	sheet := parseEditTestCue(t)
+	clone := sheet.Clone()
+
+	clone.Files[0].Tracks[1].Indices[1].Time = Timecode{Minutes: 1}
+	clone.Files[0].Tracks[0].Title = "Changed"
+	if sheet.Files[0].Tracks[1].StartTime().Minutes != 4 || sheet.Files[0].Tracks[0].Title != "One" {
+		t.Error("modifying the clone changed the original")
+	}
+	if got := clone.Files[0].Tracks[0].Duration().Minutes(); got != 1 {
+		t.Errorf("clone track 1 duration got %v minutes, want 1", got)
+	}
+}
+
+// TestCuesheet_EditOperations checks the result of each editing operation.
+func TestCuesheet_EditOperations(t *testing.T) {
+	testCases := []struct {
+		name string
+		edit func(*Cuesheet) error
+		want string
+	}{
+		{
+			name: "merge",
+			edit: func(c *Cuesheet) error { return c.MergeTracks(1, 2) },
+			want: "01:One:00:00:00 02:Three:08:00:00",
+		},
+		{
+			name: "split at sub-index",
+			edit: func(c *Cuesheet) error { return c.SplitTrackAt(2, Timecode{Minutes: 5}) },
+			want: "01:One:00:00:00 02:Two:04:00:00 03::05:00:00 04:Three:08:00:00",
+		},
+		{
+			name: "remove",
+			edit: func(c *Cuesheet) error { return c.RemoveTrack(2) },
+			want: "01:One:00:00:00 02:Three:08:00:00",
+		},
+		{
+			name: "insert",
+			edit: func(c *Cuesheet) error {
+				return c.InsertTrack(c.Files[0], &Track{Title: "New", Type: "AUDIO", Indices: []Index{{Number: 1, Time: Timecode{Minutes: 9}}}})
+			},
+			want: "01:One:00:00:00 02:Two:04:00:00 03:Three:08:00:00 04:New:09:00:00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet := parseEditTestCue(t)
+			if err := tc.edit(sheet); err != nil {
+				t.Fatalf("edit returned an unexpected error: %v", err)
+			}
+			if got := trackSummary(sheet); got != tc.want {
+				t.Errorf("got tracks %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestCuesheet_MergeAndSplitIndices checks index bookkeeping of merge and split.
+func TestCuesheet_MergeAndSplitIndices(t *testing.T) {
+	sheet := parseEditTestCue(t)
+	if err := sheet.MergeTracks(1, 2); err != nil {
+		t.Fatalf("MergeTracks() returned an unexpected error: %v", err)
+	}
+	merged := sheet.Files[0].Tracks[0]
+	var got []string
+	for _, idx := range merged.Indices {
+		got = append(got, fmt.Sprintf("%02d@%s", idx.Number, idx.Time))
+	}
+	if want := "01@00:00:00 02@04:00:00 03@05:00:00"; strings.Join(got, " ") != want {
+		t.Errorf("merged indices got %q, want %q", strings.Join(got, " "), want)
+	}
+
+	sheet = parseEditTestCue(t)
+	if err := sheet.SplitTrackAt(2, Timecode{Minutes: 4, Seconds: 30}); err != nil {
+		t.Fatalf("SplitTrackAt() returned an unexpected error: %v", err)
+	}
+	original, split := sheet.Files[0].Tracks[1], sheet.Files[0].Tracks[2]
+	if len(original.Indices) != 2 || len(split.Indices) != 2 || split.Indices[1].Number != 2 {
+		t.Errorf("split indices got %+v and %+v", original.Indices, split.Indices)
+	}
+	if got := original.Duration().Seconds(); got != 30 {
+		t.Errorf("original track duration after split got %vs, want 30s", got)
+	}
+}
+
+// TestCuesheet_EditRejectsInvalid checks that invalid edits are refused and leave the sheet intact.
+func TestCuesheet_EditRejectsInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		edit func(*Cuesheet) error
+	}{
+		{"merge non-adjacent", func(c *Cuesheet) error { return c.MergeTracks(1, 3) }},
+		{"merge missing", func(c *Cuesheet) error { return c.MergeTracks(3, 4) }},
+		{"merge over a POSTGAP", func(c *Cuesheet) error {
+			c.Track(1).Postgap = Timecode{Seconds: 2}
+			return c.MergeTracks(1, 2)
+		}},
+		{"split before start", func(c *Cuesheet) error { return c.SplitTrackAt(2, Timecode{Minutes: 3}) }},
+		{"split after end", func(c *Cuesheet) error { return c.SplitTrackAt(2, Timecode{Minutes: 8}) }},
+		{"remove missing", func(c *Cuesheet) error { return c.RemoveTrack(7) }},
+		{"insert without INDEX 01", func(c *Cuesheet) error {
+			return c.InsertTrack(c.Files[0], &Track{Indices: []Index{{Number: 0}}})
+		}},
+		{"insert into sub-indexed range", func(c *Cuesheet) error {
+			return c.InsertTrack(c.Files[0], &Track{Indices: []Index{{Number: 1, Time: Timecode{Minutes: 4, Seconds: 30}}}})
+		}},
+		{"insert at existing start", func(c *Cuesheet) error {
+			return c.InsertTrack(c.Files[0], &Track{Indices: []Index{{Number: 1, Time: Timecode{Minutes: 8}}}})
+		}},
+		{"insert into foreign file", func(c *Cuesheet) error {
+			return c.InsertTrack(&File{}, &Track{Indices: []Index{{Number: 1}}})
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet := parseEditTestCue(t)
+			before := trackSummary(sheet)
+			if err := tc.edit(sheet); err == nil {
+				t.Fatal("edit did not return an error, but one was expected")
+			}
+			if got := trackSummary(sheet); got != before {
+				t.Errorf("refused edit changed the sheet: got %q, want %q", got, before)
+			}
+		})
+	}
+
+	single := NewCuesheet()
+	single.AddFile("a.wav", "WAVE").AddTrack(1, "AUDIO")
+	if err := single.RemoveTrack(1); err == nil {
+		t.Error("RemoveTrack() removed the only track")
+	}
+	if err := single.RemoveTrack(2); !errors.Is(err, ErrTrackNotFound) {
+		t.Errorf("RemoveTrack(2) got error %v, want ErrTrackNotFound", err)
+	}
+}
+
+// TestCuesheet_InsertTrackSpanning checks that an inserted track cannot land
+// after the next track's INDEX 00 at the end of this file.
+func TestCuesheet_InsertTrackSpanning(t *testing.T) {
+	cue := "FILE \"01.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 00 04:00:00\nFILE \"02.wav\" WAVE\nINDEX 01 00:00:00"
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	late := &Track{Type: "AUDIO", Indices: []Index{{Number: 1, Time: Timecode{Minutes: 5}}}}
+	if err := sheet.InsertTrack(sheet.Files[0], late); err == nil {
+		t.Error("InsertTrack() accepted a track after the next INDEX 00")
+	}
+	early := &Track{Type: "AUDIO", Indices: []Index{{Number: 1, Time: Timecode{Minutes: 2}}}}
+	if err := sheet.InsertTrack(sheet.Files[0], early); err != nil {
+		t.Fatalf("InsertTrack() returned an unexpected error: %v", err)
+	}
+	if got, want := trackSummary(sheet), "01::00:00:00 02::02:00:00 03::00:00:00"; got != want {
+		t.Errorf("tracks got %q, want %q", got, want)
+	}
+}
+
+// TestCuesheet_SplitTrackAtSpanning checks that a split is bounded by the next
+// track's INDEX 00 when it lives at the end of this file.
+func TestCuesheet_SplitTrackAtSpanning(t *testing.T) {
+	cue := "FILE \"01.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 00 04:10:20\nFILE \"02.wav\" WAVE\nINDEX 01 00:00:00"
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if err := sheet.SplitTrackAt(1, Timecode{Minutes: 5}); err == nil {
+		t.Error("SplitTrackAt() accepted a position after the next INDEX 00")
+	}
+	if err := sheet.SplitTrackAt(1, Timecode{Minutes: 2}); err != nil {
+		t.Errorf("SplitTrackAt() returned an unexpected error: %v", err)
+	}
+}
+
+// TestCuesheet_RemoveTrackEmptiesFile checks that a FILE left without tracks
+// is removed unless another track still has indices in it.
+func TestCuesheet_RemoveTrackEmptiesFile(t *testing.T) {
+	testCases := []struct {
+		name      string
+		cue       string
+		remove    int
+		wantFiles []string
+	}{
+		{
+			name:      "file per track",
+			cue:       "FILE \"1.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nFILE \"2.wav\" WAVE\nTRACK 02 AUDIO\nINDEX 01 00:00:00\nFILE \"3.wav\" WAVE\nTRACK 03 AUDIO\nINDEX 01 00:00:00",
+			remove:    2,
+			wantFiles: []string{"1.wav", "3.wav"},
+		},
+		{
+			name:      "gap of the next track kept",
+			cue:       "FILE \"1.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 00 04:10:20\nFILE \"2.wav\" WAVE\nINDEX 01 00:00:00",
+			remove:    1,
+			wantFiles: []string{"1.wav", "2.wav"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet, err := Parse(strings.NewReader(tc.cue))
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			if err := sheet.RemoveTrack(tc.remove); err != nil {
+				t.Fatalf("RemoveTrack() returned an unexpected error: %v", err)
+			}
+			var names []string
+			for _, f := range sheet.Files {
+				names = append(names, f.Name)
+			}
+			if strings.Join(names, " ") != strings.Join(tc.wantFiles, " ") {
+				t.Errorf("files got %v, want %v", names, tc.wantFiles)
+			}
+
+			var b strings.Builder
+			if err := Write(&b, sheet); err != nil {
+				t.Fatalf("Write() returned an unexpected error: %v", err)
+			}
+			got, err := Parse(strings.NewReader(b.String()))
+			if err != nil {
+				t.Fatalf("Parse() of written sheet returned an unexpected error: %v\n%s", err, b.String())
+			}
+			if trackSummary(got) != trackSummary(sheet) {
+				t.Errorf("written sheet got %q, want %q", trackSummary(got), trackSummary(sheet))
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package embedded извлекает CUE sheet, встроенные в теги аудиофайлов.
+// foobar2000 и CUETools сохраняют весь текст CUE в поле CUESHEET комментариев
+// Vorbis (FLAC) или тега APEv2 (WavPack, Monkey's Audio, TAK).
+package embedded
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/theurs/gocue"
+	"github.com/theurs/gocue/apev2"
+	"github.com/theurs/gocue/flac"
+)
+
+// ErrNotFound возвращается, если в тегах файла нет встроенного CUE sheet.
+var ErrNotFound = errors.New("no embedded cuesheet")
+
+// tagKey — ключ поля с текстом CUE в комментариях Vorbis и тегах APEv2.
+const tagKey = "CUESHEET"
+
+// Text возвращает текст CUE, встроенный в аудиофайл path. Для FLAC он ищется
+// в комментариях Vorbis, для остальных файлов — в теге APEv2 в конце файла.
+// Метка порядка байтов UTF-8 в начале текста удаляется.
+func Text(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(f, magic[:]); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if err == io.EOF { // Пустой файл.
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	var text string
+	if string(magic[:]) == "fLaC" {
+		text, err = flacText(f)
+	} else {
+		text, err = apeText(f)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(text, "\uFEFF"), nil
+}
+
+// ReadFile извлекает встроенный CUE sheet из аудиофайла path и разбирает его.
+// Все блоки FILE указывают на сам аудиофайл: имена из встроенного текста
+// обычно ссылаются на исходный образ (например, "CDImage.wav"), которого нет.
+func ReadFile(path string) (*gocue.Cuesheet, error) {
+	text, err := Text(path)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := gocue.Parse(strings.NewReader(text))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range sheet.Files {
+		f.Name = filepath.Base(path)
+	}
+	return sheet, nil
+}
+
+// flacText ищет текст CUE в комментариях Vorbis файла FLAC.
+func flacText(f *os.File) (string, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	blocks, err := flac.ReadBlocks(bufio.NewReader(f))
+	if err != nil {
+		return "", err
+	}
+	pos := slices.IndexFunc(blocks, func(b flac.Block) bool { return b.Type == flac.BlockVorbisComment })
+	if pos < 0 {
+		return "", ErrNotFound
+	}
+	vc, err := flac.ParseVorbisComment(blocks[pos].Data)
+	if err != nil {
+		return "", err
+	}
+	values := vc.Get(tagKey)
+	if len(values) == 0 {
+		return "", ErrNotFound
+	}
+	return values[0], nil
+}
+
+// apeText ищет текст CUE в теге APEv2 в конце файла.
+func apeText(f *os.File) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	tag, err := apev2.Read(f, info.Size())
+	if errors.Is(err, apev2.ErrNoTag) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	item, ok := tag.Get(tagKey)
+	if !ok || item.Type() != apev2.ItemText {
+		return "", ErrNotFound
+	}
+	return string(item.Value), nil
+}
@@ -0,0 +1,100 @@
+package embedded
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/theurs/gocue/flac"
+	"github.com/theurs/gocue/tags"
+)
+
+const testCue = "\uFEFFPERFORMER \"Alice\"\r\nTITLE \"Album\"\r\nFILE \"CDImage.wav\" WAVE\r\n" +
+	"  TRACK 01 AUDIO\r\n    INDEX 01 00:00:00\r\n  TRACK 02 AUDIO\r\n    INDEX 01 03:00:00\r\n"
+
+// flacFile собирает минимальный FLAC с комментариями vc.
+func flacFile(vc *flac.VorbisComment) []byte {
+	data := []byte("fLaC")
+	data = append(data, byte(flac.BlockStreamInfo), 0, 0, 34)
+	data = append(data, make([]byte, 34)...)
+	comment := vc.Bytes()
+	data = append(data, 0x80|byte(flac.BlockVorbisComment), byte(len(comment)>>16), byte(len(comment)>>8), byte(len(comment)))
+	data = append(data, comment...)
+	return append(data, "\xff\xf8 audio"...)
+}
+
+// apeFile собирает файл с аудиоданными и тегом APEv2 из элементов key=value.
+func apeFile(magic string, items map[string]string) []byte {
+	var body []byte
+	for k, v := range items {
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(v)))
+		body = binary.LittleEndian.AppendUint32(body, 0)
+		body = append(body, k...)
+		body = append(body, 0)
+		body = append(body, v...)
+	}
+	data := append([]byte(magic), " audio"...)
+	data = append(data, body...)
+	data = append(data, "APETAGEX"...)
+	data = binary.LittleEndian.AppendUint32(data, 2000)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(body)+32))
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(items)))
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	return append(data, make([]byte, 8)...)
+}
+
+// TestReadFile checks extraction from Vorbis comments and APEv2 tags.
+func TestReadFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name: "flac",
+			file: "album.flac",
+			data: flacFile(&flac.VorbisComment{Vendor: "test", Comments: []tags.Tag{{Key: "cuesheet", Value: testCue}}}),
+		},
+		{name: "wavpack", file: "album.wv", data: apeFile("wvpk", map[string]string{"Cuesheet": testCue})},
+		{name: "monkey's audio", file: "album.ape", data: apeFile("MAC ", map[string]string{"CUESHEET": testCue})},
+		{name: "tak", file: "album.tak", data: apeFile("tBaK", map[string]string{"CueSheet": testCue})},
+		{
+			name:    "flac without cuesheet",
+			file:    "plain.flac",
+			data:    flacFile(&flac.VorbisComment{Vendor: "test"}),
+			wantErr: ErrNotFound,
+		},
+		{name: "ape without cuesheet", file: "plain.ape", data: apeFile("MAC ", map[string]string{"Artist": "Alice"}), wantErr: ErrNotFound},
+		{name: "no tag", file: "plain.wv", data: []byte("wvpk audio only"), wantErr: ErrNotFound},
+		{name: "empty file", file: "empty.wv", data: nil, wantErr: ErrNotFound},
+		{name: "tiny file", file: "tiny.wv", data: []byte("wv"), wantErr: ErrNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := os.WriteFile(path, tc.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			sheet, err := ReadFile(path)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("ReadFile() got error %v, want %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFile() returned an unexpected error: %v", err)
+			}
+			if sheet.Performer != "Alice" || len(sheet.Files) != 1 || len(sheet.Files[0].Tracks) != 2 {
+				t.Fatalf("got %+v", sheet)
+			}
+			if sheet.Files[0].Name != tc.file {
+				t.Errorf("File.Name got %q, want %q", sheet.Files[0].Name, tc.file)
+			}
+		})
+	}
+}
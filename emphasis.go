@@ -0,0 +1,234 @@
+package gocue
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	// FlagPreEmphasis — флаг субкода, означающий, что аудио записано с предыскажением 50/15 мкс.
+	FlagPreEmphasis = "PRE"
+
+	// RemDeemphasis — ключ REM, которым Deemphasize отмечает трек с уже
+	// скорректированными предыскажениями ("REM DEEMPHASIS 50/15us").
+	RemDeemphasis = "DEEMPHASIS"
+
+	// emphasisT1 и emphasisT2 — постоянные времени стандартной кривой предыскажения CD (50/15 мкс).
+	emphasisT1 = 50e-6
+	emphasisT2 = 15e-6
+)
+
+// HasFlag сообщает, установлен ли у трека указанный флаг субкода.
+// Сравнение выполняется без учета регистра.
+func (t *Track) HasFlag(flag string) bool {
+	for _, f := range t.Flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveFlag удаляет указанный флаг субкода из трека, если он установлен.
+// Например, после коррекции предыскажений у выходного трека нужно снять флаг PRE.
+func (t *Track) RemoveFlag(flag string) {
+	flags := t.Flags[:0]
+	for _, f := range t.Flags {
+		if !strings.EqualFold(f, flag) {
+			flags = append(flags, f)
+		}
+	}
+	t.Flags = flags
+}
+
+// PreEmphasis сообщает, помечен ли трек флагом PRE.
+// Такой трек перед воспроизведением или перекодированием нужно пропустить
+// через DeemphasisFilter.
+func (t *Track) PreEmphasis() bool {
+	return t.HasFlag(FlagPreEmphasis)
+}
+
+// Deemphasize подготавливает запись аудио трека в новый файл. Если трек
+// помечен флагом PRE, возвращается DeemphasisWriter поверх w, у трека
+// снимается флаг PRE, а коррекция записывается в метаданные комментарием
+// "REM DEEMPHASIS 50/15us" в Track.Rem (пакет tags переносит его в тег
+// DEEMPHASIS). Для трека без PRE w возвращается без изменений, обернутым
+// в io.WriteCloser, Close которого ничего не делает. Закрывайте результат
+// после записи: DeemphasisWriter.Close сообщает о неполном последнем отсчете.
+// Вызывайте метод у копии CUE sheet, описывающей выходные файлы, а не у
+// исходного: после него трек больше не помечен как PRE.
+func (t *Track) Deemphasize(w io.Writer, sampleRate, channels int) (io.WriteCloser, error) {
+	if !t.PreEmphasis() {
+		return nopCloser{w}, nil
+	}
+	dw, err := NewDeemphasisWriter(w, sampleRate, channels)
+	if err != nil {
+		return nil, err
+	}
+	t.RemoveFlag(FlagPreEmphasis)
+	if _, ok := t.RemValue(RemDeemphasis); !ok {
+		t.Rem = append(t.Rem, RemDeemphasis+" 50/15us")
+	}
+	return dw, nil
+}
+
+// DeemphasisFilter реализует стандартный фильтр коррекции предыскажений 50/15 мкс.
+// Это полочный БИХ-фильтр первого порядка с частотами среза 3183 Гц и 10610 Гц.
+// Фильтр хранит состояние между вызовами, поэтому один экземпляр нужно
+// использовать для одного непрерывного потока PCM.
+type DeemphasisFilter struct {
+	b0, b1, a1 float64
+	channels   int
+	x1, y1     []float64 // Предыдущие входной и выходной отсчеты по каждому каналу.
+	pos        int       // Текущий канал в чередующемся (interleaved) потоке.
+}
+
+// NewDeemphasisFilter создает фильтр для PCM с указанной частотой дискретизации
+// и количеством чередующихся каналов.
+func NewDeemphasisFilter(sampleRate, channels int) (*DeemphasisFilter, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d", sampleRate)
+	}
+	if channels <= 0 {
+		return nil, fmt.Errorf("invalid channel count: %d", channels)
+	}
+
+	// Полюс и ноль переносим в z-плоскость согласованным z-преобразованием
+	// (z = e^(-1/(τ·fs))): на частотах CD оно ближе к аналоговой кривой, чем
+	// билинейное преобразование. Коэффициенты нормированы на единичное усиление на DC.
+	fs := float64(sampleRate)
+	pole := math.Exp(-1 / (emphasisT1 * fs))
+	zero := math.Exp(-1 / (emphasisT2 * fs))
+	gain := (1 - pole) / (1 - zero)
+
+	return &DeemphasisFilter{
+		b0:       gain,
+		b1:       -gain * zero,
+		a1:       -pole,
+		channels: channels,
+		x1:       make([]float64, channels),
+		y1:       make([]float64, channels),
+	}, nil
+}
+
+// Reset сбрасывает внутреннее состояние фильтра, например перед новым треком.
+func (f *DeemphasisFilter) Reset() {
+	clear(f.x1)
+	clear(f.y1)
+	f.pos = 0
+}
+
+// Process применяет фильтр к чередующимся 16-битным отсчетам на месте.
+// Длина среза не обязана быть кратна количеству каналов: позиция канала
+// сохраняется между вызовами.
+func (f *DeemphasisFilter) Process(samples []int16) {
+	for i, s := range samples {
+		samples[i] = f.step(float64(s))
+	}
+}
+
+// step обрабатывает один отсчет текущего канала и возвращает результат,
+// округленный и ограниченный диапазоном int16.
+func (f *DeemphasisFilter) step(x float64) int16 {
+	ch := f.pos
+	y := f.b0*x + f.b1*f.x1[ch] - f.a1*f.y1[ch]
+	f.x1[ch] = x
+	f.y1[ch] = y
+
+	f.pos++
+	if f.pos == f.channels {
+		f.pos = 0
+	}
+
+	y = math.Round(y)
+	if y > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if y < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(y)
+}
+
+// DeemphasisWriter оборачивает io.Writer и применяет DeemphasisFilter к
+// проходящему через него 16-битному little-endian PCM. Такой формат
+// используют и блок данных WAV, и аудиотреки образов BIN.
+type DeemphasisWriter struct {
+	w        io.Writer
+	filter   *DeemphasisFilter
+	buf      []byte
+	carry    byte // Первый байт неполного отсчета с предыдущего вызова Write.
+	hasCarry bool
+}
+
+// ErrPartialSample возвращается DeemphasisWriter.Close, если в потоке осталось
+// нечетное число байтов и последний 16-битный отсчет не был записан.
+var ErrPartialSample = errors.New("pcm stream ends with a partial sample")
+
+// nopCloser добавляет к io.Writer пустой метод Close.
+type nopCloser struct {
+	io.Writer
+}
+
+// Close ничего не делает.
+func (nopCloser) Close() error { return nil }
+
+// NewDeemphasisWriter создает DeemphasisWriter поверх w.
+func NewDeemphasisWriter(w io.Writer, sampleRate, channels int) (*DeemphasisWriter, error) {
+	if w == nil {
+		return nil, errors.New("writer cannot be nil")
+	}
+	filter, err := NewDeemphasisFilter(sampleRate, channels)
+	if err != nil {
+		return nil, err
+	}
+	return &DeemphasisWriter{w: w, filter: filter}, nil
+}
+
+// Write фильтрует p и записывает результат в нижележащий io.Writer.
+// Если в конце p остается неполный отсчет, его байт откладывается до следующего
+// вызова Write или до Close.
+func (dw *DeemphasisWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	dw.buf = dw.buf[:0]
+
+	if dw.hasCarry && len(p) > 0 {
+		dw.buf = dw.appendSample(dw.buf, dw.carry, p[0])
+		dw.hasCarry = false
+		p = p[1:]
+	}
+	for len(p) >= 2 {
+		dw.buf = dw.appendSample(dw.buf, p[0], p[1])
+		p = p[2:]
+	}
+	if len(p) == 1 {
+		dw.carry = p[0]
+		dw.hasCarry = true
+	}
+
+	if _, err := dw.w.Write(dw.buf); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close завершает поток. Нижележащий io.Writer не закрывается. Если после
+// последнего Write остался байт неполного отсчета, он отбрасывается и
+// возвращается ErrPartialSample.
+func (dw *DeemphasisWriter) Close() error {
+	if dw.hasCarry {
+		dw.hasCarry = false
+		return ErrPartialSample
+	}
+	return nil
+}
+
+// appendSample фильтрует один little-endian отсчет и дописывает результат в buf.
+func (dw *DeemphasisWriter) appendSample(buf []byte, lo, hi byte) []byte {
+	s := dw.filter.step(float64(int16(uint16(lo) | uint16(hi)<<8)))
+	return binary.LittleEndian.AppendUint16(buf, uint16(s))
+}
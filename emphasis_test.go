@@ -0,0 +1,166 @@
+package gocue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestDeemphasisFilter_Response checks the filter gain against the analog 50/15 µs curve.
+func TestDeemphasisFilter_Response(t *testing.T) {
+	testCases := []struct {
+		name   string
+		signal func(i int) float64
+		wantDB float64
+	}{
+		{name: "DC passes unchanged", signal: func(int) float64 { return 10000 }, wantDB: 0},
+		// Аналоговая кривая 50/15 мкс ослабляет 1 кГц на 0.37 дБ, а 10 кГц — на 7.6 дБ.
+		{name: "1 kHz is barely attenuated", signal: sine(1000), wantDB: -0.37},
+		{name: "10 kHz is attenuated", signal: sine(10000), wantDB: -7.60},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewDeemphasisFilter(44100, 1)
+			if err != nil {
+				t.Fatalf("NewDeemphasisFilter() returned an unexpected error: %v", err)
+			}
+			samples := make([]int16, 4000)
+			for i := range samples {
+				samples[i] = int16(tc.signal(i))
+			}
+			f.Process(samples)
+
+			// Измеряем амплитуду на установившемся участке.
+			peak := 0.0
+			for _, s := range samples[2000:] {
+				peak = math.Max(peak, math.Abs(float64(s)))
+			}
+			gotDB := 20 * math.Log10(peak/10000)
+			if math.Abs(gotDB-tc.wantDB) > 0.25 {
+				t.Errorf("got gain %.2f dB, want %.2f dB", gotDB, tc.wantDB)
+			}
+		})
+	}
+}
+
+// sine возвращает генератор синусоиды с частотой freq Гц при 44.1 кГц.
+func sine(freq float64) func(i int) float64 {
+	return func(i int) float64 {
+		return 10000 * math.Sin(2*math.Pi*freq*float64(i)/44100)
+	}
+}
+
+// TestDeemphasisWriter_SplitWrites checks that odd-sized writes produce the same output as Process.
+func TestDeemphasisWriter_SplitWrites(t *testing.T) {
+	samples := make([]int16, 1000)
+	for i := range samples {
+		samples[i] = int16(8000 * math.Sin(float64(i)/3))
+	}
+	var pcm []byte
+	for _, s := range samples {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(s))
+	}
+
+	f, _ := NewDeemphasisFilter(44100, 2)
+	want := append([]int16(nil), samples...)
+	f.Process(want)
+
+	var out bytes.Buffer
+	w, err := NewDeemphasisWriter(&out, 44100, 2)
+	if err != nil {
+		t.Fatalf("NewDeemphasisWriter() returned an unexpected error: %v", err)
+	}
+	for chunk := pcm; len(chunk) > 0; {
+		n := min(len(chunk), 7)
+		if _, err := w.Write(chunk[:n]); err != nil {
+			t.Fatalf("Write() returned an unexpected error: %v", err)
+		}
+		chunk = chunk[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() returned an unexpected error: %v", err)
+	}
+
+	if out.Len() != len(pcm) {
+		t.Fatalf("got %d bytes, want %d", out.Len(), len(pcm))
+	}
+	for i, s := range want {
+		got := int16(binary.LittleEndian.Uint16(out.Bytes()[i*2:]))
+		if got != s {
+			t.Fatalf("sample %d: got %d, want %d", i, got, s)
+		}
+	}
+}
+
+// TestDeemphasisWriter_PartialSample checks that Close reports a dangling odd byte.
+func TestDeemphasisWriter_PartialSample(t *testing.T) {
+	var out bytes.Buffer
+	w, _ := NewDeemphasisWriter(&out, 44100, 2)
+	if _, err := w.Write([]byte{1, 2, 3}); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	if err := w.Close(); !errors.Is(err, ErrPartialSample) {
+		t.Errorf("Close() got %v, want ErrPartialSample", err)
+	}
+	if out.Len() != 2 {
+		t.Errorf("got %d bytes, want 2", out.Len())
+	}
+}
+
+// TestTrack_PreEmphasisFlag checks flag lookup and removal.
+func TestTrack_PreEmphasisFlag(t *testing.T) {
+	track := &Track{Flags: []string{"DCP", "pre"}}
+	if !track.PreEmphasis() {
+		t.Fatal("PreEmphasis() = false, want true")
+	}
+	track.RemoveFlag(FlagPreEmphasis)
+	if track.PreEmphasis() {
+		t.Error("PreEmphasis() = true after RemoveFlag, want false")
+	}
+	if len(track.Flags) != 1 || track.Flags[0] != "DCP" {
+		t.Errorf("got flags %v, want [DCP]", track.Flags)
+	}
+}
+
+// TestTrack_Deemphasize checks that the output track loses PRE and records the correction.
+func TestTrack_Deemphasize(t *testing.T) {
+	sheet := NewCuesheet()
+	track, _ := sheet.AddFile("a.wav", "WAVE").AddTrack(1, "AUDIO")
+	track.Flags = []string{"PRE", "DCP"}
+
+	var out bytes.Buffer
+	w, err := track.Deemphasize(&out, 44100, 2)
+	if err != nil {
+		t.Fatalf("Deemphasize() returned an unexpected error: %v", err)
+	}
+	if _, ok := w.(*DeemphasisWriter); !ok {
+		t.Errorf("Deemphasize() returned %T, want *DeemphasisWriter", w)
+	}
+	if track.PreEmphasis() {
+		t.Error("track is still flagged PRE")
+	}
+	if v, ok := track.RemValue(RemDeemphasis); !ok || v != "50/15us" {
+		t.Errorf("REM DEEMPHASIS got %q, %v", v, ok)
+	}
+	if len(sheet.Rem) != 0 {
+		t.Errorf("Cuesheet.Rem got %q, want only disc comments", sheet.Rem)
+	}
+
+	// Трек без PRE пишется как есть.
+	plain, err := track.Deemphasize(&out, 44100, 2)
+	if err != nil {
+		t.Fatalf("Deemphasize() of a plain track returned an unexpected error: %v", err)
+	}
+	if nop, ok := plain.(nopCloser); !ok || nop.Writer != &out {
+		t.Errorf("Deemphasize() of a plain track got %T; want the writer itself", plain)
+	}
+	if err := plain.Close(); err != nil {
+		t.Errorf("Close() of a plain track returned an unexpected error: %v", err)
+	}
+	if len(track.Rem) != 1 {
+		t.Errorf("Deemphasize() added REM twice: %q", track.Rem)
+	}
+}
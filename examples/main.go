@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -45,12 +46,64 @@ func findActualAudioFile(cuePath, filenameFromCue string) (string, error) {
 	return "", fmt.Errorf("audio file '%s' not found, and no alternatives could be found", filenameFromCue)
 }
 
+// buildCommand формирует команду ffmpeg для извлечения одного трека.
+func buildCommand(sourceAudioPath string, track *gocue.Track, title string) string {
+	startTime := track.StartTime().AsDuration()
+	duration := track.Duration()
+
+	outputFileName := fmt.Sprintf("%02d - %s.ogg", track.Number, title)
+	outputFileName = sanitizeFilename(outputFileName)
+
+	var cmd string
+	baseCmd := fmt.Sprintf(
+		`ffmpeg -i "%s" -ss %f -vn -map_metadata -1`,
+		sourceAudioPath,
+		startTime.Seconds(),
+	)
+
+	if duration > 0 {
+		cmd = fmt.Sprintf(
+			`%s -t %f -c:a libvorbis -q:a 5 "%s"`,
+			baseCmd,
+			duration.Seconds(),
+			outputFileName,
+		)
+	} else {
+		cmd = fmt.Sprintf(
+			`%s -c:a libvorbis -q:a 5 "%s"`,
+			baseCmd,
+			outputFileName,
+		)
+	}
+
+	// Effective подставляет исполнителя альбома, если у трека он не указан.
+	meta := track.Effective()
+	metadataCmd := fmt.Sprintf(
+		` -metadata artist="%s" -metadata album_artist="%s" -metadata album="%s" -metadata title="%s" -metadata track="%d"`,
+		meta.Artist,
+		meta.AlbumArtist,
+		meta.Album,
+		title,
+		track.Number,
+	)
+	if meta.Date != "" {
+		metadataCmd += fmt.Sprintf(` -metadata date="%s"`, meta.Date)
+	}
+	if meta.Genre != "" {
+		metadataCmd += fmt.Sprintf(` -metadata genre="%s"`, meta.Genre)
+	}
+
+	return cmd + metadataCmd
+}
+
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run main.go <path/to/your.cue>")
+	htoa := flag.Bool("htoa", false, "extract hidden track one audio (HTOA) as track 00")
+	flag.Parse()
+	if flag.NArg() < 1 {
+		fmt.Println("Usage: go run main.go [-htoa] <path/to/your.cue>")
 		os.Exit(1)
 	}
-	cuePath := os.Args[1]
+	cuePath := flag.Arg(0)
 
 	f, err := os.Open(cuePath)
 	if err != nil {
@@ -74,45 +127,13 @@ func main() {
 			continue
 		}
 
+		// Скрытый трек (HTOA) лежит в предзазоре первого трека.
+		if hidden := sheet.HiddenTrack(); *htoa && hidden != nil && hidden.IndexFile(hidden.Indices[0]) == file {
+			fmt.Println(buildCommand(sourceAudioPath, hidden, "Hidden Track"))
+		}
+
 		for _, track := range file.Tracks {
-			startTime := track.StartTime().AsDuration()
-			duration := track.Duration()
-
-			outputFileName := fmt.Sprintf("%02d - %s.ogg", track.Number, track.Title)
-			outputFileName = sanitizeFilename(outputFileName)
-
-			var cmd string
-			baseCmd := fmt.Sprintf(
-				`ffmpeg -i "%s" -ss %f -vn -map_metadata -1`,
-				sourceAudioPath,
-				startTime.Seconds(),
-			)
-
-			if duration > 0 {
-				cmd = fmt.Sprintf(
-					`%s -t %f -c:a libvorbis -q:a 5 "%s"`,
-					baseCmd,
-					duration.Seconds(),
-					outputFileName,
-				)
-			} else {
-				cmd = fmt.Sprintf(
-					`%s -c:a libvorbis -q:a 5 "%s"`,
-					baseCmd,
-					outputFileName,
-				)
-			}
-
-			metadataCmd := fmt.Sprintf(
-				` -metadata artist="%s" -metadata album_artist="%s" -metadata album="%s" -metadata title="%s" -metadata track="%d"`,
-				track.Performer,
-				sheet.Performer,
-				sheet.Title,
-				track.Title,
-				track.Number,
-			)
-
-			fmt.Println(cmd + metadataCmd)
+			fmt.Println(buildCommand(sourceAudioPath, track, track.Title))
 		}
 	}
 }
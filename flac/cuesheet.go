@@ -0,0 +1,335 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/theurs/gocue"
+)
+
+// Номера трека lead-out в блоке CUESHEET.
+const (
+	LeadOutCD    = 170 // Для CD-DA.
+	LeadOutNonCD = 255 // Для прочих источников.
+)
+
+// Ключи Cuesheet.Ext, в которых CueSheet.Cuesheet сохраняет поля блока, не
+// имеющие команд CUE. В текст CUE они не записываются, а FromCuesheet
+// учитывает их, поэтому блок переживает перевод в CUE sheet и обратно.
+const (
+	ExtLeadIn = "flac.leadin" // Число сэмплов lead-in (uint64).
+	ExtIsCD   = "flac.iscd"   // Признак CD-DA (bool).
+)
+
+// cdLeadIn — длина lead-in CD-DA в сэмплах (2 секунды), которую записывает
+// эталонный кодировщик.
+const cdLeadIn = 2 * gocue.CDSampleRate
+
+// ErrNoCueSheet возвращается, если в файле нет блока CUESHEET.
+var ErrNoCueSheet = errors.New("no CUESHEET block")
+
+// CueSheet — содержимое блока CUESHEET. Все смещения заданы в сэмплах.
+type CueSheet struct {
+	MCN    string // Media Catalog Number (CATALOG).
+	LeadIn uint64 // Число сэмплов lead-in; для не-CD равно 0.
+	IsCD   bool   // Блок соответствует диску CD-DA.
+
+	// Tracks — треки в порядке следования; последним идет трек lead-out
+	// (LeadOutCD или LeadOutNonCD), смещение которого равно длине потока.
+	Tracks []CueTrack
+}
+
+// CueTrack — трек блока CUESHEET.
+type CueTrack struct {
+	Offset      uint64 // Смещение первого индекса трека от начала потока.
+	Number      uint8
+	ISRC        string
+	Audio       bool
+	PreEmphasis bool
+	Indices     []CueIndex
+}
+
+// CueIndex — индекс трека; смещение отсчитывается от начала трека.
+type CueIndex struct {
+	Offset uint64
+	Number uint8
+}
+
+// errShortCueSheet возвращается для усеченного блока CUESHEET.
+var errShortCueSheet = errors.New("truncated CUESHEET block")
+
+// ParseCueSheet разбирает данные блока CUESHEET.
+func ParseCueSheet(data []byte) (*CueSheet, error) {
+	// Каталог (128), lead-in (8), флаг CD и резерв (259), число треков (1).
+	if len(data) < 396 {
+		return nil, errShortCueSheet
+	}
+	cs := &CueSheet{
+		MCN:    cString(data[:128]),
+		LeadIn: binary.BigEndian.Uint64(data[128:]),
+		IsCD:   data[136]&0x80 != 0,
+	}
+	count := int(data[395])
+	data = data[396:]
+
+	for range count {
+		// Смещение (8), номер (1), ISRC (12), флаги и резерв (14), число индексов (1).
+		if len(data) < 36 {
+			return nil, errShortCueSheet
+		}
+		t := CueTrack{
+			Offset:      binary.BigEndian.Uint64(data),
+			Number:      data[8],
+			ISRC:        cString(data[9:21]),
+			Audio:       data[21]&0x80 == 0,
+			PreEmphasis: data[21]&0x40 != 0,
+		}
+		indices := int(data[35])
+		data = data[36:]
+		if len(data) < indices*12 {
+			return nil, errShortCueSheet
+		}
+		for range indices {
+			t.Indices = append(t.Indices, CueIndex{Offset: binary.BigEndian.Uint64(data), Number: data[8]})
+			data = data[12:]
+		}
+		cs.Tracks = append(cs.Tracks, t)
+	}
+	return cs, nil
+}
+
+// Bytes кодирует блок CUESHEET.
+func (cs *CueSheet) Bytes() []byte {
+	buf := make([]byte, 0, 396+len(cs.Tracks)*48)
+	buf = append(buf, padded(cs.MCN, 128)...)
+	buf = binary.BigEndian.AppendUint64(buf, cs.LeadIn)
+	flags := make([]byte, 259)
+	if cs.IsCD {
+		flags[0] = 0x80
+	}
+	buf = append(buf, flags...)
+	buf = append(buf, byte(len(cs.Tracks)))
+
+	for _, t := range cs.Tracks {
+		buf = binary.BigEndian.AppendUint64(buf, t.Offset)
+		buf = append(buf, t.Number)
+		buf = append(buf, padded(t.ISRC, 12)...)
+		flags := make([]byte, 14)
+		if !t.Audio {
+			flags[0] |= 0x80
+		}
+		if t.PreEmphasis {
+			flags[0] |= 0x40
+		}
+		buf = append(buf, flags...)
+		buf = append(buf, byte(len(t.Indices)))
+		for _, idx := range t.Indices {
+			buf = binary.BigEndian.AppendUint64(buf, idx.Offset)
+			buf = append(buf, idx.Number, 0, 0, 0)
+		}
+	}
+	return buf
+}
+
+// Cuesheet переводит блок в CUE sheet с одним файлом fileName типа WAVE.
+// Позиции переводятся из сэмплов в таймкоды для частоты sampleRate; если
+// позиция не попадает на границу фрейма, точное значение сохраняется
+// в Index.Precise. Трек lead-out в CUE sheet не попадает. Lead-in и признак
+// CD-DA сохраняются в Cuesheet.Ext (ExtLeadIn и ExtIsCD). Для блока не-CD
+// допускаются номера треков до 254, которых нет в формате CUE для дисков.
+func (cs *CueSheet) Cuesheet(fileName string, sampleRate int) (*gocue.Cuesheet, error) {
+	if sampleRate <= 0 {
+		return nil, errors.New("sample rate is required")
+	}
+	c := gocue.NewCuesheet()
+	c.Catalog = cs.MCN
+	c.Ext = map[string]any{ExtLeadIn: cs.LeadIn, ExtIsCD: cs.IsCD}
+	file := c.AddFile(fileName, "WAVE")
+	leadOut := uint8(LeadOutNonCD)
+	if cs.IsCD {
+		leadOut = LeadOutCD
+	}
+	for i, ct := range cs.Tracks {
+		if i == len(cs.Tracks)-1 && ct.Number == leadOut {
+			continue
+		}
+		trackType := "AUDIO"
+		if !ct.Audio {
+			trackType = "MODE1/2352"
+		}
+		var t *gocue.Track
+		if !cs.IsCD && ct.Number > 99 {
+			// AddTrack принимает только номера CD; уникальность проверяем сами.
+			if c.Track(int(ct.Number)) != nil {
+				return nil, fmt.Errorf("track %d: %w", ct.Number, gocue.ErrDuplicateTrack)
+			}
+			t = &gocue.Track{Number: int(ct.Number), Type: trackType}
+			file.Tracks = append(file.Tracks, t)
+		} else {
+			var err error
+			if t, err = file.AddTrack(int(ct.Number), trackType); err != nil {
+				return nil, err
+			}
+		}
+		t.ISRC = ct.ISRC
+		if ct.PreEmphasis {
+			t.Flags = append(t.Flags, gocue.FlagPreEmphasis)
+		}
+		for _, ci := range ct.Indices {
+			samples := ct.Offset + ci.Offset
+			tc, exact := gocue.TimecodeFromSamples(int64(samples), sampleRate)
+			idx := gocue.Index{Number: int(ci.Number), Time: tc}
+			if !exact {
+				// Делим отдельно целые секунды и остаток, чтобы не переполнить int64.
+				rate := uint64(sampleRate)
+				idx.Precise = time.Duration(samples/rate)*time.Second + time.Duration(samples%rate*uint64(time.Second)/rate)
+			}
+			t.Indices = append(t.Indices, idx)
+		}
+	}
+	c.Relink()
+	return c, nil
+}
+
+// FromCuesheet строит блок CUESHEET для CUE sheet c, описывающего один файл
+// с параметрами потока info. Поток CD-DA (44100 Гц, 16 бит, стерео) дает
+// блок CD-DA, как и у эталонного кодировщика: позиции кратны 588 сэмплам,
+// lead-in равен 2 секундам, а трек lead-out имеет номер LeadOutCD. Позиции
+// индексов с Index.Precise переводятся в сэмплы точно, кроме режима CD-DA,
+// где используется таймкод. Значения Cuesheet.Ext, сохраненные методом
+// CueSheet.Cuesheet, заменяют lead-in, а ExtIsCD, равный false, отключает
+// режим CD-DA; вне CD-DA допускаются номера треков до 254. PREGAP и POSTGAP
+// в блок не попадают: этой тишины нет в аудиопотоке.
+func FromCuesheet(c *gocue.Cuesheet, info StreamInfo) (*CueSheet, error) {
+	if len(c.Files) != 1 {
+		return nil, fmt.Errorf("%w: CUESHEET block describes a single file, got %d", gocue.ErrLayoutUnsupported, len(c.Files))
+	}
+	sampleRate := info.SampleRate
+	if sampleRate <= 0 {
+		return nil, errors.New("sample rate is required")
+	}
+	isCD := sampleRate == gocue.CDSampleRate && info.BitsPerSample == 16 && info.Channels == 2
+	if v, ok := c.Ext[ExtIsCD].(bool); ok && !v {
+		isCD = false
+	}
+	cs := &CueSheet{MCN: c.Catalog, IsCD: isCD}
+	leadOut, maxTrack := uint8(LeadOutNonCD), LeadOutNonCD-1
+	if isCD {
+		cs.LeadIn, leadOut, maxTrack = cdLeadIn, LeadOutCD, 99
+		if v, ok := c.Ext[ExtLeadIn].(uint64); ok {
+			cs.LeadIn = v
+		}
+	}
+	if len(cs.MCN) > 128 {
+		return nil, fmt.Errorf("catalog number is too long: %q", cs.MCN)
+	}
+
+	for _, t := range c.Files[0].Tracks {
+		if t.Number < 1 || t.Number > maxTrack {
+			return nil, fmt.Errorf("track %d: %w", t.Number, gocue.ErrInvalidNumber)
+		}
+		if len(t.Indices) == 0 {
+			return nil, fmt.Errorf("track %d has no indices", t.Number)
+		}
+		isrc := strings.ToUpper(strings.ReplaceAll(t.ISRC, "-", ""))
+		if isrc != "" && len(isrc) != 12 {
+			return nil, fmt.Errorf("track %d: invalid ISRC %q", t.Number, t.ISRC)
+		}
+
+		ct := CueTrack{Number: uint8(t.Number), ISRC: isrc, Audio: t.IsAudio(), PreEmphasis: t.HasFlag(gocue.FlagPreEmphasis)}
+		for i, idx := range t.Indices {
+			samples := uint64(idx.Time.Samples(sampleRate))
+			if !isCD {
+				samples = uint64(idx.Samples(sampleRate))
+			}
+			if i == 0 {
+				ct.Offset = samples
+			}
+			if samples < ct.Offset {
+				return nil, fmt.Errorf("track %d: index %02d goes back in time", t.Number, idx.Number)
+			}
+			ct.Indices = append(ct.Indices, CueIndex{Offset: samples - ct.Offset, Number: uint8(idx.Number)})
+		}
+		if n := len(cs.Tracks); n > 0 && ct.Offset < cs.Tracks[n-1].Offset {
+			return nil, fmt.Errorf("track %d starts before track %d", t.Number, cs.Tracks[n-1].Number)
+		}
+		cs.Tracks = append(cs.Tracks, ct)
+	}
+	if len(cs.Tracks) == 0 {
+		return nil, errors.New("cuesheet has no tracks")
+	}
+	if last := cs.Tracks[len(cs.Tracks)-1]; info.TotalSamples < last.Offset {
+		return nil, fmt.Errorf("track %d starts after the end of the stream", last.Number)
+	}
+	cs.Tracks = append(cs.Tracks, CueTrack{Offset: info.TotalSamples, Number: leadOut, Audio: true})
+	return cs, nil
+}
+
+// ReadCuesheet читает встроенный блок CUESHEET файла path и возвращает его
+// в виде CUE sheet, ссылающегося на сам файл. Если блока нет, возвращается ErrNoCueSheet.
+func ReadCuesheet(path string) (*gocue.Cuesheet, error) {
+	blocks, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := streamInfo(blocks)
+	if err != nil {
+		return nil, err
+	}
+	pos := slices.IndexFunc(blocks, func(b Block) bool { return b.Type == BlockCueSheet })
+	if pos < 0 {
+		return nil, ErrNoCueSheet
+	}
+	cs, err := ParseCueSheet(blocks[pos].Data)
+	if err != nil {
+		return nil, err
+	}
+	return cs.Cuesheet(filepath.Base(path), info.SampleRate)
+}
+
+// WriteCuesheet записывает CUE sheet c в файл path блоком CUESHEET, заменяя
+// имеющийся. Частота и длина потока берутся из STREAMINFO файла; аудиофреймы
+// не изменяются (см. WriteFile).
+func WriteCuesheet(path string, c *gocue.Cuesheet) error {
+	blocks, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := streamInfo(blocks)
+	if err != nil {
+		return err
+	}
+	cs, err := FromCuesheet(c, info)
+	if err != nil {
+		return err
+	}
+
+	block := Block{Type: BlockCueSheet, Data: cs.Bytes()}
+	if pos := slices.IndexFunc(blocks, func(b Block) bool { return b.Type == BlockCueSheet }); pos >= 0 {
+		blocks[pos] = block
+	} else {
+		blocks = append(blocks, block)
+	}
+	return WriteFile(path, blocks)
+}
+
+// cString возвращает строку из поля фиксированной длины, дополненного нулями.
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// padded возвращает s в поле длины n, дополненном нулями.
+func padded(s string, n int) []byte {
+	b := make([]byte, n)
+	copy(b, s)
+	return b
+}
@@ -0,0 +1,257 @@
+package flac
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theurs/gocue"
+)
+
+// testStreamInfo кодирует STREAMINFO стереопотока 16 бит с частотой rate
+// и длиной total сэмплов.
+func testStreamInfo(rate int, total uint64) []byte {
+	data := make([]byte, 34)
+	data[10] = byte(rate >> 12)
+	data[11] = byte(rate >> 4)
+	data[12] = byte(rate<<4) | 1<<1 | 0 // 2 канала, старший бит (16-1).
+	data[13] = 0xf0 | byte(total>>32&0x0f)
+	data[14], data[15], data[16], data[17] = byte(total>>24), byte(total>>16), byte(total>>8), byte(total)
+	return data
+}
+
+// cdStreamInfo возвращает параметры потока CD-DA длиной total сэмплов.
+func cdStreamInfo(total uint64) StreamInfo {
+	return StreamInfo{SampleRate: 44100, Channels: 2, BitsPerSample: 16, TotalSamples: total}
+}
+
+// TestParseStreamInfo checks decoding of the packed STREAMINFO fields.
+func TestParseStreamInfo(t *testing.T) {
+	info, err := ParseStreamInfo(testStreamInfo(44100, 1<<33+5))
+	if err != nil {
+		t.Fatalf("ParseStreamInfo() returned an unexpected error: %v", err)
+	}
+	want := StreamInfo{SampleRate: 44100, Channels: 2, BitsPerSample: 16, TotalSamples: 1<<33 + 5}
+	if info != want {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+const testCueSheetCue = `
+CATALOG 0074643135527
+FILE "image.wav" WAVE
+  TRACK 01 AUDIO
+    ISRC US-SM1-59-00113
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    FLAGS PRE
+    INDEX 00 03:00:00
+    INDEX 01 03:02:37
+    INDEX 02 04:00:00
+`
+
+// TestFromCuesheet_CD checks the exact frame to sample mapping of a CD-DA block.
+func TestFromCuesheet_CD(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(testCueSheetCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	cs, err := FromCuesheet(sheet, cdStreamInfo(44100*300))
+	if err != nil {
+		t.Fatalf("FromCuesheet() returned an unexpected error: %v", err)
+	}
+
+	want := &CueSheet{
+		MCN:    "0074643135527",
+		LeadIn: 88200,
+		IsCD:   true,
+		Tracks: []CueTrack{
+			{Offset: 0, Number: 1, ISRC: "USSM15900113", Audio: true, Indices: []CueIndex{{0, 1}}},
+			{Offset: 180 * 44100, Number: 2, Audio: true, PreEmphasis: true, Indices: []CueIndex{
+				{0, 0},
+				{(2*75 + 37) * 588, 1},
+				{60 * 44100, 2},
+			}},
+			{Offset: 44100 * 300, Number: LeadOutCD, Audio: true},
+		},
+	}
+	if cs.MCN != want.MCN || cs.LeadIn != want.LeadIn || cs.IsCD != want.IsCD || len(cs.Tracks) != len(want.Tracks) {
+		t.Fatalf("got %+v, want %+v", cs, want)
+	}
+	for i := range want.Tracks {
+		g, w := cs.Tracks[i], want.Tracks[i]
+		if g.Offset != w.Offset || g.Number != w.Number || g.ISRC != w.ISRC || g.Audio != w.Audio ||
+			g.PreEmphasis != w.PreEmphasis || !slices.Equal(g.Indices, w.Indices) {
+			t.Errorf("track %d got %+v, want %+v", i, g, w)
+		}
+		for _, idx := range g.Indices {
+			if (g.Offset+idx.Offset)%588 != 0 {
+				t.Errorf("track %d index %d is not on a CD frame boundary", g.Number, idx.Number)
+			}
+		}
+	}
+
+	// Блок переживает кодирование и обратный перевод в CUE sheet.
+	parsed, err := ParseCueSheet(cs.Bytes())
+	if err != nil {
+		t.Fatalf("ParseCueSheet() returned an unexpected error: %v", err)
+	}
+	back, err := parsed.Cuesheet("image.flac", 44100)
+	if err != nil {
+		t.Fatalf("Cuesheet() returned an unexpected error: %v", err)
+	}
+	if back.Catalog != sheet.Catalog || back.Files[0].Name != "image.flac" {
+		t.Errorf("got catalog %q, file %q", back.Catalog, back.Files[0].Name)
+	}
+	for i, tr := range back.Files[0].Tracks {
+		orig := sheet.Files[0].Tracks[i]
+		if tr.Number != orig.Number || !slices.Equal(tr.Indices, orig.Indices) || tr.PreEmphasis() != orig.PreEmphasis() {
+			t.Errorf("track %d got %+v, want %+v", i, tr, orig)
+		}
+	}
+}
+
+// TestCueSheet_PreciseNonCD checks sample offsets that fall between frames.
+func TestCueSheet_PreciseNonCD(t *testing.T) {
+	cs := &CueSheet{Tracks: []CueTrack{
+		{Offset: 0, Number: 1, Audio: true, Indices: []CueIndex{{0, 1}}},
+		{Offset: 96000*10 + 1, Number: 2, Audio: true, Indices: []CueIndex{{0, 1}}},
+		{Offset: 96000 * 20, Number: LeadOutNonCD, Audio: true},
+	}}
+	sheet, err := cs.Cuesheet("hires.flac", 96000)
+	if err != nil {
+		t.Fatalf("Cuesheet() returned an unexpected error: %v", err)
+	}
+	idx, _ := sheet.Track(2).Index(1)
+	if idx.Time != (gocue.Timecode{Seconds: 10}) || idx.Precise != 10*time.Second+10416 {
+		t.Errorf("got index %+v", idx)
+	}
+
+	out, err := FromCuesheet(sheet, StreamInfo{SampleRate: 96000, Channels: 2, BitsPerSample: 24, TotalSamples: 96000 * 20})
+	if err != nil {
+		t.Fatalf("FromCuesheet() returned an unexpected error: %v", err)
+	}
+	if out.IsCD || out.LeadIn != 0 || out.Tracks[1].Offset != cs.Tracks[1].Offset || out.Tracks[2].Number != LeadOutNonCD {
+		t.Errorf("got %+v", out)
+	}
+}
+
+// TestWriteCuesheet checks embedding into and reading back from a file.
+func TestWriteCuesheet(t *testing.T) {
+	path := writeTestFLAC(t, t.TempDir(), "image.flac", Block{Type: BlockPadding, Data: make([]byte, 64)})
+	// Заменяем STREAMINFO тестового файла реальными параметрами.
+	blocks, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks[0].Data = testStreamInfo(44100, 44100*300)
+	if err := WriteFile(path, blocks); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadCuesheet(path); err != ErrNoCueSheet {
+		t.Errorf("ReadCuesheet() got %v, want ErrNoCueSheet", err)
+	}
+	sheet, err := gocue.Parse(strings.NewReader(testCueSheetCue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCuesheet(path, sheet); err != nil {
+		t.Fatalf("WriteCuesheet() returned an unexpected error: %v", err)
+	}
+	checkAudio(t, path)
+
+	got, err := ReadCuesheet(path)
+	if err != nil {
+		t.Fatalf("ReadCuesheet() returned an unexpected error: %v", err)
+	}
+	if got.Files[0].Name != "image.flac" || len(got.Files[0].Tracks) != 2 {
+		t.Fatalf("got %+v", got.Files[0])
+	}
+	if start := got.Track(2).StartTime(); start != (gocue.Timecode{Minutes: 3, Seconds: 2, Frames: 37}) {
+		t.Errorf("track 2 starts at %s, want 03:02:37", start)
+	}
+
+	if _, err := ParseCueSheet(bytes.Repeat([]byte{0}, 100)); err == nil {
+		t.Error("ParseCueSheet() accepted a truncated block")
+	}
+}
+
+// TestCueSheet_LeadInAndNumbering checks that lead-in, the CD flag and non-CD
+// track numbers survive a conversion to a CUE sheet and back.
+func TestCueSheet_LeadInAndNumbering(t *testing.T) {
+	testCases := []struct {
+		name string
+		cs   *CueSheet
+	}{
+		{"cd lead-in", &CueSheet{LeadIn: 44100 * 3, IsCD: true, Tracks: []CueTrack{
+			{Offset: 0, Number: 1, Audio: true, Indices: []CueIndex{{0, 1}}},
+			{Offset: 44100 * 60, Number: LeadOutCD, Audio: true},
+		}}},
+		{"non-cd at 44.1 kHz", &CueSheet{Tracks: []CueTrack{
+			{Offset: 0, Number: 1, Audio: true, Indices: []CueIndex{{0, 1}}},
+			{Offset: 44100 * 10, Number: 100, Audio: true, Indices: []CueIndex{{0, 1}}},
+			{Offset: 44100 * 20, Number: LeadOutCD, Audio: true, Indices: []CueIndex{{0, 1}}},
+			{Offset: 44100 * 60, Number: LeadOutNonCD, Audio: true},
+		}}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet, err := tc.cs.Cuesheet("image.flac", 44100)
+			if err != nil {
+				t.Fatalf("Cuesheet() returned an unexpected error: %v", err)
+			}
+			if got, want := len(sheet.Files[0].Tracks), len(tc.cs.Tracks)-1; got != want {
+				t.Fatalf("Cuesheet() got %d tracks, want %d", got, want)
+			}
+			if len(sheet.Rem) != 0 {
+				t.Errorf("Cuesheet() added comments %q", sheet.Rem)
+			}
+			last := tc.cs.Tracks[len(tc.cs.Tracks)-1]
+			out, err := FromCuesheet(sheet, cdStreamInfo(last.Offset))
+			if err != nil {
+				t.Fatalf("FromCuesheet() returned an unexpected error: %v", err)
+			}
+			if out.LeadIn != tc.cs.LeadIn || out.IsCD != tc.cs.IsCD || len(out.Tracks) != len(tc.cs.Tracks) {
+				t.Fatalf("round trip got %+v, want %+v", out, tc.cs)
+			}
+			for i, tr := range out.Tracks {
+				if tr.Number != tc.cs.Tracks[i].Number || tr.Offset != tc.cs.Tracks[i].Offset {
+					t.Errorf("track %d got %d at %d, want %d at %d", i, tr.Number, tr.Offset, tc.cs.Tracks[i].Number, tc.cs.Tracks[i].Offset)
+				}
+			}
+		})
+	}
+}
+
+// TestFromCuesheet_NonCDStream checks that only 16-bit stereo 44.1 kHz streams get a CD-DA block.
+func TestFromCuesheet_NonCDStream(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(testCueSheetCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	testCases := []struct {
+		name string
+		info StreamInfo
+		isCD bool
+	}{
+		{"cd-da", cdStreamInfo(44100 * 300), true},
+		{"24-bit", StreamInfo{SampleRate: 44100, Channels: 2, BitsPerSample: 24, TotalSamples: 44100 * 300}, false},
+		{"mono", StreamInfo{SampleRate: 44100, Channels: 1, BitsPerSample: 16, TotalSamples: 44100 * 300}, false},
+	}
+	for _, tc := range testCases {
+		cs, err := FromCuesheet(sheet, tc.info)
+		if err != nil {
+			t.Fatalf("%s: FromCuesheet() returned an unexpected error: %v", tc.name, err)
+		}
+		leadOut := uint8(LeadOutNonCD)
+		if tc.isCD {
+			leadOut = LeadOutCD
+		}
+		if cs.IsCD != tc.isCD || cs.Tracks[len(cs.Tracks)-1].Number != leadOut {
+			t.Errorf("%s: got IsCD %v, lead-out %d", tc.name, cs.IsCD, cs.Tracks[len(cs.Tracks)-1].Number)
+		}
+	}
+}
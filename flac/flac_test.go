@@ -0,0 +1,291 @@
+package flac
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/theurs/gocue"
+	"github.com/theurs/gocue/tags"
+)
+
+// testAudio заменяет аудиофреймы в тестовых файлах.
+var testAudio = []byte("\xff\xf8 audio frames that must not change")
+
+// writeTestFLAC создает в dir FLAC-файл из STREAMINFO, блоков extra и testAudio.
+func writeTestFLAC(t *testing.T, dir, name string, extra ...Block) string {
+	t.Helper()
+	blocks := append([]Block{{Type: BlockStreamInfo, Data: make([]byte, 34)}}, extra...)
+	buf, err := encodeBlocks(blocks)
+	if err != nil {
+		t.Fatalf("encodeBlocks() returned an unexpected error: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(buf, testAudio...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkAudio проверяет, что после метаданных файла лежат нетронутые аудиофреймы.
+func checkAudio(t *testing.T, path string) []Block {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := ReadBlocks(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadBlocks() returned an unexpected error: %v", err)
+	}
+	if audio := data[metadataSize(blocks):]; !bytes.Equal(audio, testAudio) {
+		t.Errorf("audio frames changed: got %q", audio)
+	}
+	return blocks
+}
+
+// TestReadBlocks_NotFLAC checks the signature check.
+func TestReadBlocks_NotFLAC(t *testing.T) {
+	for _, data := range []string{"", "RIFF....WAVE", "fLaC"} {
+		if _, err := ReadBlocks(strings.NewReader(data)); err == nil {
+			t.Errorf("ReadBlocks(%q) expected an error", data)
+		}
+	}
+}
+
+// TestVorbisComment_RoundTrip checks VORBIS_COMMENT encoding.
+func TestVorbisComment_RoundTrip(t *testing.T) {
+	vc := &VorbisComment{Vendor: "reference libFLAC 1.4.3", Comments: []tags.Tag{
+		{Key: "ARTIST", Value: "Alice"},
+		{Key: "ARTIST", Value: "Bob"},
+		{Key: "TITLE", Value: "Привет=мир"},
+	}, Invalid: []string{"no separator"}}
+	got, err := ParseVorbisComment(vc.Bytes())
+	if err != nil {
+		t.Fatalf("ParseVorbisComment() returned an unexpected error: %v", err)
+	}
+	if got.Vendor != vc.Vendor || !slices.Equal(got.Comments, vc.Comments) || !slices.Equal(got.Invalid, vc.Invalid) {
+		t.Errorf("round trip got %+v, want %+v", got, vc)
+	}
+	if artists := got.Get("artist"); !slices.Equal(artists, []string{"Alice", "Bob"}) {
+		t.Errorf("Get(artist) got %v", artists)
+	}
+	if _, err := ParseVorbisComment(vc.Bytes()[:10]); err == nil {
+		t.Error("ParseVorbisComment() accepted a truncated block")
+	}
+}
+
+// TestPicture_RoundTrip checks PICTURE encoding.
+func TestPicture_RoundTrip(t *testing.T) {
+	p := &Picture{Type: PictureFrontCover, MIME: "image/jpeg", Description: "cover", Width: 500, Height: 400, Depth: 24, Data: []byte{1, 2, 3}}
+	got, err := ParsePicture(p.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePicture() returned an unexpected error: %v", err)
+	}
+	if got.MIME != p.MIME || got.Description != p.Description || got.Width != 500 || got.Height != 400 || !bytes.Equal(got.Data, p.Data) {
+		t.Errorf("round trip got %+v, want %+v", got, p)
+	}
+}
+
+// TestNewPicture checks the MIME type, size and depth read from image headers.
+func TestNewPicture(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	rgba.Set(0, 0, color.RGBA{1, 2, 3, 128})
+	paletted := image.NewPaletted(image.Rect(0, 0, 30, 20), palette.Plan9)
+	encode := func(f func(*bytes.Buffer) error) []byte {
+		var b bytes.Buffer
+		if err := f(&b); err != nil {
+			t.Fatalf("encoding test image: %v", err)
+		}
+		return b.Bytes()
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+		want Picture
+	}{
+		{"jpeg", encode(func(b *bytes.Buffer) error { return jpeg.Encode(b, rgba, nil) }),
+			Picture{MIME: "image/jpeg", Width: 30, Height: 20, Depth: 24}},
+		{"png rgba", encode(func(b *bytes.Buffer) error { return png.Encode(b, rgba) }),
+			Picture{MIME: "image/png", Width: 30, Height: 20, Depth: 32}},
+		{"png paletted", encode(func(b *bytes.Buffer) error { return png.Encode(b, paletted) }),
+			Picture{MIME: "image/png", Width: 30, Height: 20, Depth: 8, Colors: 256}},
+		{"gif", encode(func(b *bytes.Buffer) error { return gif.Encode(b, paletted, nil) }),
+			Picture{MIME: "image/gif", Width: 30, Height: 20, Depth: 8, Colors: 256}},
+		{"unknown", []byte("not an image"), Picture{MIME: "application/octet-stream"}},
+		{"truncated jpeg", []byte("\xFF\xD8\xFF\xC0\x00"), Picture{MIME: "image/jpeg"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := NewPicture(tc.data)
+			if p.Type != PictureFrontCover || !bytes.Equal(p.Data, tc.data) {
+				t.Errorf("NewPicture() got type %d and %d bytes", p.Type, len(p.Data))
+			}
+			got := Picture{MIME: p.MIME, Width: p.Width, Height: p.Height, Depth: p.Depth, Colors: p.Colors}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("NewPicture() got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestWriteTags checks in-place and full rewrites of the metadata.
+func TestWriteTags(t *testing.T) {
+	existing := &VorbisComment{Vendor: "reference libFLAC 1.4.3", Comments: []tags.Tag{
+		{Key: "TITLE", Value: "Old"},
+		{Key: "ENCODER", Value: "flac"},
+	}, Invalid: []string{"broken entry"}}
+	testCases := []struct {
+		name    string
+		padding int
+		fields  []tags.Tag
+		want    []Change
+	}{
+		{
+			name:    "fits into padding",
+			padding: 1024,
+			fields:  []tags.Tag{{Key: "TITLE", Value: "New"}, {Key: "ARTIST", Value: "Alice"}},
+			want:    []Change{{Key: "TITLE", Old: "Old", New: "New"}, {Key: "ARTIST", New: "Alice"}},
+		},
+		{
+			name:   "needs rewrite",
+			fields: []tags.Tag{{Key: "TITLE", Value: "A much longer title than before"}},
+			want:   []Change{{Key: "TITLE", Old: "Old", New: "A much longer title than before"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			extra := []Block{{Type: BlockVorbisComment, Data: existing.Bytes()}}
+			if tc.padding > 0 {
+				extra = append(extra, Block{Type: BlockPadding, Data: make([]byte, tc.padding)})
+			}
+			path := writeTestFLAC(t, dir, "track.flac", extra...)
+			before, _ := os.Stat(path)
+
+			// Пробный режим не должен менять файл.
+			changes, err := WriteTags(path, tc.fields, TagOptions{DryRun: true})
+			if err != nil {
+				t.Fatalf("WriteTags(dry run) returned an unexpected error: %v", err)
+			}
+			if !slices.Equal(changes, tc.want) {
+				t.Errorf("WriteTags(dry run) got %v, want %v", changes, tc.want)
+			}
+			if after, _ := os.Stat(path); after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+				t.Error("dry run modified the file")
+			}
+
+			if _, err := WriteTags(path, tc.fields, TagOptions{}); err != nil {
+				t.Fatalf("WriteTags() returned an unexpected error: %v", err)
+			}
+			blocks := checkAudio(t, path)
+			after, _ := os.Stat(path)
+			if tc.padding > 0 && after.Size() != before.Size() {
+				t.Errorf("file size changed from %d to %d, want an in-place update", before.Size(), after.Size())
+			}
+			vc, err := ParseVorbisComment(blocks[1].Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if vc.Vendor != existing.Vendor || len(vc.Get("ENCODER")) != 1 || vc.Get("TITLE")[0] != tc.fields[0].Value || !slices.Equal(vc.Invalid, existing.Invalid) {
+				t.Errorf("got comments %+v", vc)
+			}
+
+			// Повторная запись тех же тегов ничего не меняет.
+			if changes, err := WriteTags(path, tc.fields, TagOptions{}); err != nil || len(changes) != 0 {
+				t.Errorf("second WriteTags() got %v, %v; want no changes", changes, err)
+			}
+		})
+	}
+}
+
+// TestWriteTags_Picture checks that a cover replaces the picture of the same type.
+func TestWriteTags_Picture(t *testing.T) {
+	old := &Picture{Type: PictureFrontCover, MIME: "image/png", Data: []byte("old")}
+	back := &Picture{Type: 4, MIME: "image/png", Data: []byte("back")}
+	path := writeTestFLAC(t, t.TempDir(), "track.flac",
+		Block{Type: BlockPicture, Data: old.Bytes()},
+		Block{Type: BlockPicture, Data: back.Bytes()})
+
+	cover := &Picture{Type: PictureFrontCover, MIME: "image/jpeg", Data: []byte("new")}
+	changes, err := WriteTags(path, nil, TagOptions{Picture: cover})
+	if err != nil {
+		t.Fatalf("WriteTags() returned an unexpected error: %v", err)
+	}
+	if len(changes) != 1 || changes[0].Key != "PICTURE" {
+		t.Errorf("got changes %v, want a PICTURE change", changes)
+	}
+
+	var pictures []string
+	for _, b := range checkAudio(t, path) {
+		if b.Type == BlockPicture {
+			p, err := ParsePicture(b.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pictures = append(pictures, string(p.Data))
+		}
+	}
+	if !slices.Equal(pictures, []string{"back", "new"}) {
+		t.Errorf("got pictures %v, want [back new]", pictures)
+	}
+}
+
+// TestTagCuesheet checks matching per-track files to the tracks of a disc image cue.
+func TestTagCuesheet(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(`
+PERFORMER "Alice"
+TITLE "Album"
+FILE "CDImage.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 01 03:00:00
+  TRACK 03 AUDIO
+    TITLE "Three"
+    INDEX 01 06:00:00
+`))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	dir := t.TempDir()
+	one := writeTestFLAC(t, dir, "01 - One.flac")
+	writeTestFLAC(t, dir, "02 - Two.flac")
+	writeTestFLAC(t, dir, "12 - Other.flac")
+
+	results, err := TagCuesheet(sheet, dir, TagOptions{})
+	if err != nil {
+		t.Fatalf("TagCuesheet() returned an unexpected error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	if results[0].Err != nil || results[1].Err != nil || results[2].Err == nil {
+		t.Errorf("got errors %v, %v, %v; want only track 3 to fail", results[0].Err, results[1].Err, results[2].Err)
+	}
+
+	vc, err := ParseVorbisComment(checkAudio(t, one)[1].Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vc.Get("TITLE"); !slices.Equal(got, []string{"One"}) {
+		t.Errorf("TITLE got %v, want [One]", got)
+	}
+	if got := vc.Get("TRACKNUMBER"); !slices.Equal(got, []string{"1"}) {
+		t.Errorf("TRACKNUMBER got %v, want [1]", got)
+	}
+}
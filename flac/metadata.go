@@ -0,0 +1,232 @@
+// Package flac читает и записывает блоки метаданных FLAC, не затрагивая
+// аудиофреймы: комментарии Vorbis, обложки (PICTURE) и встроенные CUE sheet.
+package flac
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BlockType — тип блока метаданных FLAC.
+type BlockType uint8
+
+// Типы блоков метаданных по спецификации FLAC.
+const (
+	BlockStreamInfo    BlockType = 0
+	BlockPadding       BlockType = 1
+	BlockApplication   BlockType = 2
+	BlockSeekTable     BlockType = 3
+	BlockVorbisComment BlockType = 4
+	BlockCueSheet      BlockType = 5
+	BlockPicture       BlockType = 6
+)
+
+// DefaultPadding — размер блока PADDING, который оставляется после метаданных,
+// если файл приходится переписывать целиком. Запас позволяет последующим
+// изменениям тегов обходиться без перезаписи аудио.
+const DefaultPadding = 8192
+
+// maxBlockLength — максимальная длина блока: поле длины занимает 24 бита.
+const maxBlockLength = 1<<24 - 1
+
+var (
+	// ErrNotFLAC возвращается, если данные не начинаются с сигнатуры "fLaC".
+	ErrNotFLAC = errors.New("not a FLAC file")
+	// ErrBlockTooLarge возвращается, если блок не помещается в 24-битное поле длины.
+	ErrBlockTooLarge = errors.New("metadata block is too large")
+)
+
+// Block — один блок метаданных FLAC. Признак последнего блока не хранится:
+// он выставляется при записи.
+type Block struct {
+	Type BlockType
+	Data []byte
+}
+
+// ReadBlocks читает сигнатуру и все блоки метаданных из r. После успешного
+// вызова r указывает на первый аудиофрейм.
+func ReadBlocks(r io.Reader) ([]Block, error) {
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotFLAC, err)
+	}
+	if string(magic[:]) != "fLaC" {
+		return nil, ErrNotFLAC
+	}
+
+	var blocks []Block
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("metadata block header: %w", err)
+		}
+		last := header[0]&0x80 != 0
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		block := Block{Type: BlockType(header[0] & 0x7f), Data: make([]byte, length)}
+		if _, err := io.ReadFull(r, block.Data); err != nil {
+			return nil, fmt.Errorf("metadata block of type %d: %w", block.Type, err)
+		}
+		if len(blocks) == 0 && block.Type != BlockStreamInfo {
+			return nil, errors.New("first metadata block is not STREAMINFO")
+		}
+		blocks = append(blocks, block)
+		if last {
+			return blocks, nil
+		}
+	}
+}
+
+// ReadFile читает блоки метаданных файла path.
+func ReadFile(path string) ([]Block, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBlocks(bufio.NewReader(f))
+}
+
+// metadataSize возвращает размер сигнатуры и всех блоков, то есть смещение
+// первого аудиофрейма в файле.
+func metadataSize(blocks []Block) int64 {
+	size := int64(4)
+	for _, b := range blocks {
+		size += 4 + int64(len(b.Data))
+	}
+	return size
+}
+
+// encodeBlocks записывает сигнатуру и блоки; последний блок получает
+// соответствующий признак.
+func encodeBlocks(blocks []Block) ([]byte, error) {
+	buf := make([]byte, 0, metadataSize(blocks))
+	buf = append(buf, "fLaC"...)
+	for i, b := range blocks {
+		if len(b.Data) > maxBlockLength {
+			return nil, fmt.Errorf("%w: type %d, %d bytes", ErrBlockTooLarge, b.Type, len(b.Data))
+		}
+		header := byte(b.Type) & 0x7f
+		if i == len(blocks)-1 {
+			header |= 0x80
+		}
+		buf = append(buf, header, byte(len(b.Data)>>16), byte(len(b.Data)>>8), byte(len(b.Data)))
+		buf = append(buf, b.Data...)
+	}
+	return buf, nil
+}
+
+// WriteFile заменяет метаданные файла path блоками blocks; аудиофреймы
+// не изменяются. Первым должен идти STREAMINFO, блоки PADDING отбрасываются.
+// Если новые метаданные помещаются на место старых, файл переписывается
+// на месте, а остаток заполняется блоком PADDING. Иначе файл пересобирается
+// во временном файле с DefaultPadding байт запаса, который затем заменяет исходный.
+func WriteFile(path string, blocks []Block) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	old, err := ReadBlocks(bufio.NewReader(f))
+	f.Close()
+	if err != nil {
+		return err
+	}
+	audioOffset := metadataSize(old)
+
+	var kept []Block
+	for _, b := range blocks {
+		if b.Type != BlockPadding {
+			kept = append(kept, b)
+		}
+	}
+	if len(kept) == 0 || kept[0].Type != BlockStreamInfo {
+		return errors.New("first metadata block must be STREAMINFO")
+	}
+
+	// Место под блок PADDING есть, если остаток не меньше его заголовка.
+	size := metadataSize(kept)
+	switch free := audioOffset - size; {
+	case free == 0:
+		return writeInPlace(path, kept)
+	case free >= 4 && free-4 <= maxBlockLength:
+		return writeInPlace(path, append(kept, Block{Type: BlockPadding, Data: make([]byte, free-4)}))
+	}
+	return rewrite(path, append(kept, Block{Type: BlockPadding, Data: make([]byte, DefaultPadding)}), audioOffset)
+}
+
+// writeInPlace перезаписывает начало файла метаданными того же размера.
+func writeInPlace(path string, blocks []Block) error {
+	buf, err := encodeBlocks(blocks)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(buf, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewrite собирает файл заново во временном файле рядом с исходным и заменяет
+// им исходный. Аудиоданные копируются начиная со смещения audioOffset.
+func rewrite(path string, blocks []Block, audioOffset int64) (err error) {
+	buf, err := encodeBlocks(blocks)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".gocue-*.flac")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(buf); err != nil {
+		return err
+	}
+	if _, err = io.Copy(tmp, io.NewSectionReader(src, audioOffset, info.Size()-audioOffset)); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// le32 дописывает в buf число в порядке little-endian, как в комментариях Vorbis.
+func le32(buf []byte, n int) []byte {
+	return binary.LittleEndian.AppendUint32(buf, uint32(n))
+}
+
+// be32 дописывает в buf число в порядке big-endian, как в остальных блоках FLAC.
+func be32(buf []byte, n int) []byte {
+	return binary.BigEndian.AppendUint32(buf, uint32(n))
+}
@@ -0,0 +1,189 @@
+package flac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// PictureFrontCover — тип изображения "обложка альбома" (как в APIC ID3v2).
+const PictureFrontCover = 3
+
+// Picture — содержимое блока PICTURE.
+type Picture struct {
+	Type        uint32 // Тип изображения, например PictureFrontCover.
+	MIME        string
+	Description string
+	Width       uint32
+	Height      uint32
+	Depth       uint32 // Бит на пиксель.
+	Colors      uint32 // Число цветов для палитровых изображений, иначе 0.
+	Data        []byte
+}
+
+// NewPicture создает блок обложки из содержимого файла изображения.
+// MIME-тип определяется по сигнатуре файла, а для JPEG, PNG и GIF из
+// заголовка также читаются размеры и глубина цвета. Само изображение
+// не декодируется.
+func NewPicture(data []byte) *Picture {
+	p := &Picture{Type: PictureFrontCover, MIME: "application/octet-stream", Data: data}
+	switch {
+	case bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")):
+		p.MIME = "image/jpeg"
+		p.jpegHeader(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1A\n")):
+		p.MIME = "image/png"
+		p.pngHeader(data)
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		p.MIME = "image/gif"
+		p.gifHeader(data)
+	case bytes.HasPrefix(data, []byte("BM")):
+		p.MIME = "image/bmp"
+	case len(data) >= 12 && bytes.HasPrefix(data, []byte("RIFF")) && string(data[8:12]) == "WEBP":
+		p.MIME = "image/webp"
+	}
+	return p
+}
+
+// jpegHeader читает размеры и глубину цвета из маркера SOF.
+func (p *Picture) jpegHeader(data []byte) {
+	data = data[2:]
+	for len(data) >= 4 {
+		if data[0] != 0xFF {
+			return
+		}
+		marker := data[1]
+		switch {
+		case marker == 0xFF: // Заполняющий байт.
+			data = data[1:]
+			continue
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD7: // Маркеры без длины.
+			data = data[2:]
+			continue
+		}
+		n := int(binary.BigEndian.Uint16(data[2:]))
+		if n < 2 || len(data) < 2+n {
+			return
+		}
+		// SOF0-SOF15, кроме DHT (C4), JPG (C8) и DAC (CC).
+		if marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC {
+			if n < 8 {
+				return
+			}
+			seg := data[4:]
+			p.Height = uint32(binary.BigEndian.Uint16(seg[1:]))
+			p.Width = uint32(binary.BigEndian.Uint16(seg[3:]))
+			p.Depth = uint32(seg[0]) * uint32(seg[5])
+			return
+		}
+		data = data[2+n:]
+	}
+}
+
+// pngHeader читает размеры и глубину цвета из IHDR, а для палитровых
+// изображений — число цветов из PLTE.
+func (p *Picture) pngHeader(data []byte) {
+	data = data[8:]
+	for len(data) >= 12 {
+		n := binary.BigEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-12) {
+			return
+		}
+		chunk, body := string(data[4:8]), data[8:8+n]
+		switch chunk {
+		case "IHDR":
+			if len(body) < 10 {
+				return
+			}
+			p.Width = binary.BigEndian.Uint32(body)
+			p.Height = binary.BigEndian.Uint32(body[4:])
+			// Число каналов по типу цвета: серый, RGB, палитра, серый с альфой, RGBA.
+			channels := map[byte]uint32{0: 1, 2: 3, 3: 1, 4: 2, 6: 4}[body[9]]
+			p.Depth = uint32(body[8]) * channels
+			if body[9] != 3 {
+				return
+			}
+		case "PLTE":
+			p.Colors = n / 3
+			return
+		case "IDAT", "IEND":
+			return
+		}
+		data = data[12+n:]
+	}
+}
+
+// gifHeader читает размеры и глобальную палитру из логического дескриптора экрана.
+func (p *Picture) gifHeader(data []byte) {
+	if len(data) < 13 {
+		return
+	}
+	p.Width = uint32(binary.LittleEndian.Uint16(data[6:]))
+	p.Height = uint32(binary.LittleEndian.Uint16(data[8:]))
+	bits := uint32(data[10]&0x07) + 1
+	p.Depth = bits
+	if data[10]&0x80 != 0 {
+		p.Colors = 1 << bits
+	}
+}
+
+// ParsePicture разбирает данные блока PICTURE.
+func ParsePicture(data []byte) (*Picture, error) {
+	errShort := errors.New("truncated PICTURE block")
+	u32 := func() (uint32, bool) {
+		if len(data) < 4 {
+			return 0, false
+		}
+		n := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		return n, true
+	}
+	str := func() ([]byte, bool) {
+		n, ok := u32()
+		if !ok || uint64(n) > uint64(len(data)) {
+			return nil, false
+		}
+		s := data[:n]
+		data = data[n:]
+		return s, true
+	}
+
+	p := &Picture{}
+	var ok bool
+	if p.Type, ok = u32(); !ok {
+		return nil, errShort
+	}
+	mime, ok := str()
+	if !ok {
+		return nil, errShort
+	}
+	desc, ok := str()
+	if !ok {
+		return nil, errShort
+	}
+	p.MIME, p.Description = string(mime), string(desc)
+	for _, v := range []*uint32{&p.Width, &p.Height, &p.Depth, &p.Colors} {
+		if *v, ok = u32(); !ok {
+			return nil, errShort
+		}
+	}
+	if p.Data, ok = str(); !ok {
+		return nil, errShort
+	}
+	p.Data = bytes.Clone(p.Data)
+	return p, nil
+}
+
+// Bytes кодирует изображение в данные блока PICTURE.
+func (p *Picture) Bytes() []byte {
+	buf := be32(nil, int(p.Type))
+	buf = be32(buf, len(p.MIME))
+	buf = append(buf, p.MIME...)
+	buf = be32(buf, len(p.Description))
+	buf = append(buf, p.Description...)
+	for _, v := range []uint32{p.Width, p.Height, p.Depth, p.Colors} {
+		buf = be32(buf, int(v))
+	}
+	buf = be32(buf, len(p.Data))
+	return append(buf, p.Data...)
+}
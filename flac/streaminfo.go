@@ -0,0 +1,34 @@
+package flac
+
+import "errors"
+
+// StreamInfo — основные параметры потока из блока STREAMINFO.
+type StreamInfo struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	TotalSamples  uint64 // Число сэмплов на канал; 0 — неизвестно.
+}
+
+// ParseStreamInfo разбирает данные блока STREAMINFO.
+func ParseStreamInfo(data []byte) (StreamInfo, error) {
+	if len(data) < 34 {
+		return StreamInfo{}, errors.New("truncated STREAMINFO block")
+	}
+	// Байты 10-17: частота (20 бит), каналы-1 (3 бита), бит на сэмпл-1 (5 бит),
+	// общее число сэмплов (36 бит).
+	return StreamInfo{
+		SampleRate:    int(data[10])<<12 | int(data[11])<<4 | int(data[12])>>4,
+		Channels:      int(data[12]>>1&0x07) + 1,
+		BitsPerSample: int(data[12]&0x01)<<4 | int(data[13]>>4) + 1,
+		TotalSamples:  uint64(data[13]&0x0f)<<32 | uint64(data[14])<<24 | uint64(data[15])<<16 | uint64(data[16])<<8 | uint64(data[17]),
+	}, nil
+}
+
+// streamInfo возвращает параметры потока из первого блока метаданных.
+func streamInfo(blocks []Block) (StreamInfo, error) {
+	if len(blocks) == 0 || blocks[0].Type != BlockStreamInfo {
+		return StreamInfo{}, errors.New("missing STREAMINFO block")
+	}
+	return ParseStreamInfo(blocks[0].Data)
+}
@@ -0,0 +1,234 @@
+package flac
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/theurs/gocue"
+	"github.com/theurs/gocue/tags"
+)
+
+// defaultVendor — строка производителя для нового блока VORBIS_COMMENT.
+const defaultVendor = "gocue"
+
+// TagOptions задает параметры WriteTags и TagCuesheet.
+type TagOptions struct {
+	// Picture, если задан, записывается блоком PICTURE вместо имеющихся
+	// изображений того же типа.
+	Picture *Picture
+
+	// DryRun включает пробный режим: изменения вычисляются и возвращаются,
+	// но файлы не изменяются.
+	DryRun bool
+}
+
+// Change — изменение одного поля тега. Пустое Old означает, что поле
+// добавляется; несколько значений одного поля объединяются через "; ".
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// String возвращает изменение в виде "KEY: старое -> новое".
+func (c Change) String() string {
+	if c.Old == "" {
+		return fmt.Sprintf("%s: %q", c.Key, c.New)
+	}
+	return fmt.Sprintf("%s: %q -> %q", c.Key, c.Old, c.New)
+}
+
+// WriteTags записывает поля fields в блок VORBIS_COMMENT файла path
+// и возвращает список изменений. Поля с теми же ключами (без учета регистра)
+// заменяются, остальные комментарии и строка производителя сохраняются.
+// Аудиофреймы не изменяются; по возможности новые метаданные занимают место
+// старых за счет блока PADDING (см. WriteFile). Если изменений нет,
+// файл не перезаписывается.
+func WriteTags(path string, fields []tags.Tag, opts TagOptions) ([]Change, error) {
+	blocks, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vc := &VorbisComment{Vendor: defaultVendor}
+	pos := slices.IndexFunc(blocks, func(b Block) bool { return b.Type == BlockVorbisComment })
+	if pos >= 0 {
+		if vc, err = ParseVorbisComment(blocks[pos].Data); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := mergeComments(vc, fields)
+	if opts.Picture != nil {
+		var old []Block
+		blocks = slices.DeleteFunc(blocks, func(b Block) bool {
+			if b.Type != BlockPicture {
+				return false
+			}
+			p, err := ParsePicture(b.Data)
+			if err != nil || p.Type != opts.Picture.Type {
+				return false
+			}
+			old = append(old, b)
+			return true
+		})
+		data := opts.Picture.Bytes()
+		if len(old) != 1 || !slices.Equal(old[0].Data, data) {
+			changes = append(changes, Change{Key: "PICTURE", Old: describePictures(old), New: describePicture(opts.Picture)})
+		}
+		blocks = append(blocks, Block{Type: BlockPicture, Data: data})
+		pos = slices.IndexFunc(blocks, func(b Block) bool { return b.Type == BlockVorbisComment })
+	}
+	if len(changes) == 0 || opts.DryRun {
+		return changes, nil
+	}
+
+	if pos >= 0 {
+		blocks[pos].Data = vc.Bytes()
+	} else {
+		// Комментарии ставятся сразу после STREAMINFO, как это делает эталонный кодировщик.
+		blocks = slices.Insert(blocks, 1, Block{Type: BlockVorbisComment, Data: vc.Bytes()})
+	}
+	return changes, WriteFile(path, blocks)
+}
+
+// mergeComments заменяет в vc поля с ключами из fields и возвращает изменения.
+func mergeComments(vc *VorbisComment, fields []tags.Tag) []Change {
+	var keys []string // Ключи в порядке первого появления в fields.
+	values := make(map[string][]string)
+	for _, f := range fields {
+		key := strings.ToUpper(f.Key)
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], f.Value)
+	}
+
+	var changes []Change
+	for _, key := range keys {
+		old, want := strings.Join(vc.Get(key), "; "), strings.Join(values[key], "; ")
+		if old != want {
+			changes = append(changes, Change{Key: key, Old: old, New: want})
+		}
+	}
+
+	vc.Comments = slices.DeleteFunc(vc.Comments, func(c tags.Tag) bool {
+		_, ok := values[strings.ToUpper(c.Key)]
+		return ok
+	})
+	for _, key := range keys {
+		for _, v := range values[key] {
+			vc.Comments = append(vc.Comments, tags.Tag{Key: key, Value: v})
+		}
+	}
+	return changes
+}
+
+// describePicture возвращает краткое описание изображения для списка изменений.
+func describePicture(p *Picture) string {
+	if p.Width > 0 {
+		return fmt.Sprintf("%s %dx%d, %d bytes", p.MIME, p.Width, p.Height, len(p.Data))
+	}
+	return fmt.Sprintf("%s, %d bytes", p.MIME, len(p.Data))
+}
+
+// describePictures описывает удаляемые блоки PICTURE.
+func describePictures(blocks []Block) string {
+	var s []string
+	for _, b := range blocks {
+		if p, err := ParsePicture(b.Data); err == nil {
+			s = append(s, describePicture(p))
+		}
+	}
+	return strings.Join(s, "; ")
+}
+
+// TagResult — результат разметки одного файла в TagCuesheet.
+type TagResult struct {
+	Track   *gocue.Track
+	Path    string   // Путь к файлу; пуст, если файл не найден.
+	Changes []Change // Изменения тегов; в пробном режиме файл не изменен.
+	Err     error
+}
+
+// TagCuesheet размечает уже разрезанные по трекам FLAC-файлы из каталога dir
+// данными CUE sheet c: для каждого трека теги строятся через tags.Track
+// и записываются WriteTags. Ошибка одного файла не прерывает обработку
+// остальных и возвращается в TagResult.Err.
+//
+// Файл трека ищется так: если в CUE sheet на каждый FILE приходится один
+// трек, берется имя из FILE (с расширением .flac, если исходного файла нет).
+// Иначе, например для CUE образа диска, в dir ищется единственный .flac-файл,
+// имя которого начинается с двузначного номера трека: "01 - Title.flac", "01.flac".
+func TagCuesheet(c *gocue.Cuesheet, dir string, opts TagOptions) ([]TagResult, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var flacs []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ".flac") {
+			flacs = append(flacs, e.Name())
+		}
+	}
+
+	perTrack := len(c.Files) > 0
+	for _, f := range c.Files {
+		perTrack = perTrack && len(f.Tracks) == 1
+	}
+
+	var results []TagResult
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			res := TagResult{Track: t}
+			if perTrack {
+				res.Path, res.Err = trackFileByName(dir, f.Name, t.Number)
+			} else {
+				res.Path, res.Err = trackFileByNumber(dir, flacs, t.Number)
+			}
+			if res.Err == nil {
+				res.Changes, res.Err = WriteTags(res.Path, tags.Track(c, t, tags.Vorbis), opts)
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// errNoTrackFile возвращается, если файл трека не найден.
+var errNoTrackFile = errors.New("no FLAC file found for track")
+
+// trackFileByName ищет файл трека по имени name из его блока FILE.
+func trackFileByName(dir, name string, number int) (string, error) {
+	candidates := []string{name, strings.TrimSuffix(name, filepath.Ext(name)) + ".flac"}
+	for _, n := range candidates {
+		path := filepath.Join(dir, n)
+		if _, err := os.Stat(path); err == nil && strings.EqualFold(filepath.Ext(n), ".flac") {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("track %d: %w: %s", number, errNoTrackFile, name)
+}
+
+// trackFileByNumber ищет среди flacs единственный файл, имя которого
+// начинается с двузначного номера трека, за которым не следует цифра.
+func trackFileByNumber(dir string, flacs []string, number int) (string, error) {
+	prefix := fmt.Sprintf("%02d", number)
+	var found []string
+	for _, name := range flacs {
+		if rest, ok := strings.CutPrefix(name, prefix); ok && (rest == "" || rest[0] < '0' || rest[0] > '9') {
+			found = append(found, name)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("track %d: %w", number, errNoTrackFile)
+	case 1:
+		return filepath.Join(dir, found[0]), nil
+	}
+	return "", fmt.Errorf("track %d: several files match: %s", number, strings.Join(found, ", "))
+}
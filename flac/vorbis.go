@@ -0,0 +1,92 @@
+package flac
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+
+	"github.com/theurs/gocue/tags"
+)
+
+// VorbisComment — содержимое блока VORBIS_COMMENT.
+type VorbisComment struct {
+	Vendor   string
+	Comments []tags.Tag // Поля в порядке записи; ключ может повторяться.
+	// Invalid — поля без "=", которые не соответствуют спецификации. Они
+	// сохраняются как есть и записываются после Comments, чтобы перезапись
+	// тегов не удаляла данные из файла.
+	Invalid []string
+}
+
+// errShortComment возвращается для усеченного блока VORBIS_COMMENT.
+var errShortComment = errors.New("truncated VORBIS_COMMENT block")
+
+// ParseVorbisComment разбирает данные блока VORBIS_COMMENT.
+func ParseVorbisComment(data []byte) (*VorbisComment, error) {
+	next := func() (string, error) {
+		if len(data) < 4 {
+			return "", errShortComment
+		}
+		n := binary.LittleEndian.Uint32(data)
+		if uint64(n) > uint64(len(data)-4) {
+			return "", errShortComment
+		}
+		s := string(data[4 : 4+n])
+		data = data[4+n:]
+		return s, nil
+	}
+
+	vendor, err := next()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, errShortComment
+	}
+	count := binary.LittleEndian.Uint32(data)
+	data = data[4:]
+
+	vc := &VorbisComment{Vendor: vendor}
+	for range count {
+		s, err := next()
+		if err != nil {
+			return nil, err
+		}
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			vc.Invalid = append(vc.Invalid, s)
+			continue
+		}
+		vc.Comments = append(vc.Comments, tags.Tag{Key: key, Value: value})
+	}
+	return vc, nil
+}
+
+// Bytes кодирует комментарии в данные блока VORBIS_COMMENT.
+func (vc *VorbisComment) Bytes() []byte {
+	buf := le32(nil, len(vc.Vendor))
+	buf = append(buf, vc.Vendor...)
+	buf = le32(buf, len(vc.Comments)+len(vc.Invalid))
+	for _, c := range vc.Comments {
+		buf = le32(buf, len(c.Key)+1+len(c.Value))
+		buf = append(buf, c.Key...)
+		buf = append(buf, '=')
+		buf = append(buf, c.Value...)
+	}
+	for _, s := range vc.Invalid {
+		buf = le32(buf, len(s))
+		buf = append(buf, s...)
+	}
+	return buf
+}
+
+// Get возвращает все значения поля key. Ключи сравниваются без учета регистра.
+func (vc *VorbisComment) Get(key string) []string {
+	var values []string
+	for _, c := range vc.Comments {
+		if strings.EqualFold(c.Key, key) {
+			values = append(values, c.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,41 @@
+package gocue
+
+// HiddenTrack возвращает скрытый трек в предзазоре первого трека (Hidden Track
+// One Audio, HTOA), если он есть на диске. Признак HTOA — INDEX 00 первого трека
+// в 00:00:00 и INDEX 01 позже, в том же файле.
+//
+// Возвращаемый трек виртуальный: его нет в Files[].Tracks, он имеет номер 0,
+// тип и флаги первого трека и единственный INDEX 01 в 00:00:00. StartTime()
+// возвращает начало скрытого диапазона, а Duration() — его длину. Если скрытого
+// трека нет, возвращается nil.
+func (c *Cuesheet) HiddenTrack() *Track {
+	var first *Track
+	for _, t := range c.Tracks() {
+		first = t
+		break
+	}
+	if first == nil || first.Number != 1 {
+		return nil
+	}
+
+	pregap, ok := first.Index(0)
+	if !ok || pregap.Time != (Timecode{}) || !first.StartTime().After(pregap.Time) {
+		return nil
+	}
+	file := first.IndexFile(pregap)
+	if file != first.startFile() {
+		return nil // Пауза в другом файле: это не скрытый трек в начале диска.
+	}
+
+	hidden := &Track{
+		Number:     0,
+		Type:       first.Type,
+		Flags:      append([]string(nil), first.Flags...),
+		Indices:    []Index{{Number: 1}},
+		parentFile: first.parentFile,
+	}
+	if file != first.parentFile {
+		hidden.Indices[0].File = file
+	}
+	return hidden
+}
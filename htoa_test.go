@@ -0,0 +1,107 @@
+package gocue
+
+import (
+	"strings"
+	"testing"
+)
+
+const htoaCue = `
+FILE "CDImage.wav" WAVE
+  TRACK 01 AUDIO
+    FLAGS PRE
+    INDEX 00 00:00:00
+    INDEX 01 01:30:00
+  TRACK 02 AUDIO
+    INDEX 01 05:00:00
+`
+
+// TestCuesheet_HiddenTrack checks HTOA detection and the virtual track's start and length.
+func TestCuesheet_HiddenTrack(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(htoaCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+
+	hidden := sheet.HiddenTrack()
+	if hidden == nil {
+		t.Fatal("HiddenTrack() returned nil")
+	}
+	if hidden.Number != 0 || hidden.StartTime() != (Timecode{}) || !hidden.PreEmphasis() {
+		t.Errorf("unexpected hidden track: %+v", hidden)
+	}
+	if got := hidden.Duration().Seconds(); got != 90 {
+		t.Errorf("hidden track duration got %vs, want 90s", got)
+	}
+	if got := sheet.Files[0].Tracks[0].Duration().Seconds(); got != 210 {
+		t.Errorf("track 1 duration got %vs, want 210s", got)
+	}
+
+	noHTOA := []string{
+		"FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00",
+		"FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:00\nINDEX 01 00:00:00",
+		"FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 00 00:00:10\nINDEX 01 00:02:00",
+	}
+	for _, cue := range noHTOA {
+		sheet, err := Parse(strings.NewReader(cue))
+		if err != nil {
+			t.Fatalf("Parse() returned an unexpected error: %v", err)
+		}
+		if sheet.HiddenTrack() != nil {
+			t.Errorf("HiddenTrack() reported a hidden track for:\n%s", cue)
+		}
+	}
+}
+
+// TestConvertLayout_HiddenTrack checks the opt-in that emits HTOA as track 00.
+func TestConvertLayout_HiddenTrack(t *testing.T) {
+	sheet, _ := Parse(strings.NewReader(htoaCue))
+
+	testCases := []struct {
+		target Layout
+		hidden bool
+		want   string
+	}{
+		{LayoutGapsPrepended, false, "01.wav[01: 00@00:00:00 01@01:30:00] 02.wav[02: 01@00:00:00]"},
+		{LayoutGapsPrepended, true, "00.wav[00: 01@00:00:00] 01.wav[01: 01@00:00:00] 02.wav[02: 01@00:00:00]"},
+		{LayoutGapsAppended, true, "00.wav[00: 01@00:00:00] 01.wav[01: 01@00:00:00] 02.wav[02: 01@00:00:00]"},
+		{LayoutSingleFile, true, "CDImage.wav[01: 00@00:00:00 01@01:30:00; 02: 01@05:00:00]"},
+	}
+	for _, tc := range testCases {
+		out, err := ConvertLayout(sheet, tc.target, ConvertOptions{HiddenTrack: tc.hidden})
+		if err != nil {
+			t.Fatalf("ConvertLayout(%s) returned an unexpected error: %v", tc.target, err)
+		}
+		if got := layoutSummary(out); got != tc.want {
+			t.Errorf("ConvertLayout(%s, hidden=%v):\ngot  %s\nwant %s", tc.target, tc.hidden, got, tc.want)
+		}
+	}
+
+	// Пауза трека 01 — это сам скрытый трек: в файле 00 она не дублируется.
+	out, _ := ConvertLayout(sheet, LayoutNonCompliant, ConvertOptions{HiddenTrack: true})
+	if got, want := layoutSummary(out), "00.wav[00: 01@00:00:00] 01.wav[01: 01@00:00:00] 02.wav[02: 01@00:00:00]"; got != want {
+		t.Errorf("ConvertLayout(%s, hidden=true):\ngot  %s\nwant %s", LayoutNonCompliant, got, want)
+	}
+}
+
+// TestConvertLayout_FileExtensions checks that FILE extensions survive conversion.
+func TestConvertLayout_FileExtensions(t *testing.T) {
+	sheet, _ := Parse(strings.NewReader(htoaCue))
+	sheet.Files[0].Extensions = []Command{{Name: "CATALOG_HINT", Args: []string{"x"}}}
+	sheet.Files[0].Ext = map[string]any{"handler": 1}
+
+	for _, target := range []Layout{LayoutSingleFile, LayoutGapsPrepended, LayoutNonCompliant} {
+		out, err := ConvertLayout(sheet, target, ConvertOptions{HiddenTrack: true})
+		if err != nil {
+			t.Fatalf("ConvertLayout(%s) returned an unexpected error: %v", target, err)
+		}
+		first := out.Files[0]
+		if len(first.Extensions) != 1 || first.Extensions[0].Name != "CATALOG_HINT" || first.Ext["handler"] != 1 {
+			t.Errorf("ConvertLayout(%s): file extensions were lost: %+v %v", target, first.Extensions, first.Ext)
+		}
+		for _, f := range out.Files[1:] {
+			if len(f.Extensions) != 0 {
+				t.Errorf("ConvertLayout(%s): extensions duplicated into %s", target, f.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package gocue
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// timecodeJSON — JSON-представление таймкода: строка "MM:SS:FF" и общее
+// количество фреймов, чтобы потребителям не приходилось разбирать строку.
+type timecodeJSON struct {
+	Time   string `json:"time"`
+	Frames int    `json:"frames"`
+}
+
+// MarshalJSON реализует json.Marshaler.
+// Таймкод кодируется как {"time": "MM:SS:FF", "frames": N}.
+func (t Timecode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timecodeJSON{Time: t.String(), Frames: t.TotalFrames()})
+}
+
+// UnmarshalJSON реализует json.Unmarshaler. Принимается как объект в формате
+// MarshalJSON, так и просто строка "MM:SS:FF". Если в объекте нет строки
+// времени, таймкод восстанавливается из количества фреймов.
+func (t *Timecode) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return t.UnmarshalText([]byte(s))
+	}
+
+	var v timecodeJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Time == "" {
+		if v.Frames < 0 {
+			return errors.New("frames value cannot be negative")
+		}
+		*t = NewTimecodeFromFrames(v.Frames)
+		return nil
+	}
+	return t.UnmarshalText([]byte(v.Time))
+}
+
+// trackAlias нужен, чтобы вызвать стандартное кодирование Track без рекурсии в MarshalJSON.
+type trackAlias Track
+
+// trackJSON дополняет трек вычисляемыми полями. End и Duration отсутствуют,
+// если конец трека нельзя определить по данным CUE (например, для последнего трека).
+type trackJSON struct {
+	*trackAlias
+	Indices  []indexJSON `json:"indices"`
+	Start    Timecode    `json:"start"`
+	End      *Timecode   `json:"end,omitempty"`
+	Duration *Timecode   `json:"duration,omitempty"`
+}
+
+// indexJSON дополняет индекс порядковым номером файла в Cuesheet.Files,
+// если индекс лежит не в файле своего трека (см. Index.File).
+type indexJSON struct {
+	Index
+	File *int `json:"file,omitempty"`
+}
+
+// MarshalJSON реализует json.Marshaler. Помимо полей трека в JSON попадают
+// вычисленные время начала, конца и длительность.
+func (t *Track) MarshalJSON() ([]byte, error) {
+	v := trackJSON{trackAlias: (*trackAlias)(t), Start: t.StartTime()}
+	v.Indices = make([]indexJSON, len(t.Indices))
+	for i, idx := range t.Indices {
+		v.Indices[i].Index = idx
+		if idx.File != nil && idx.File != t.parentFile && t.parentFile != nil && t.parentFile.parentSheet != nil {
+			if pos := slices.Index(t.parentFile.parentSheet.Files, idx.File); pos >= 0 {
+				v.Indices[i].File = &pos
+			}
+		}
+	}
+	if t.parentFile != nil {
+		if next := t.parentFile.findNextTrack(t); next != nil {
+			end := next.StartTime()
+			if !end.Before(v.Start) {
+				duration := end.Sub(v.Start)
+				v.End, v.Duration = &end, &duration
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+// cuesheetAlias нужен, чтобы вызвать стандартное декодирование Cuesheet без рекурсии в UnmarshalJSON.
+type cuesheetAlias Cuesheet
+
+// UnmarshalJSON реализует json.Unmarshaler. После декодирования восстанавливаются
+// внутренние ссылки на родительские элементы, поэтому методы вроде
+// track.Duration() работают так же, как после Parse.
+func (c *Cuesheet) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*cuesheetAlias)(c)); err != nil {
+		return err
+	}
+
+	for i, f := range c.Files {
+		if f == nil {
+			return fmt.Errorf("file %d is null", i)
+		}
+		for j, t := range f.Tracks {
+			if t == nil {
+				return fmt.Errorf("file %d: track %d is null", i, j)
+			}
+		}
+	}
+
+	// Восстанавливаем ссылки индексов на файлы из их порядковых номеров.
+	var refs struct {
+		Files []struct {
+			Tracks []struct {
+				Indices []struct {
+					File *int `json:"file"`
+				} `json:"indices"`
+			} `json:"tracks"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(data, &refs); err != nil {
+		return err
+	}
+	for i, f := range refs.Files {
+		for j, t := range f.Tracks {
+			for k, idx := range t.Indices {
+				if idx.File == nil {
+					continue
+				}
+				if *idx.File < 0 || *idx.File >= len(c.Files) {
+					return fmt.Errorf("index refers to unknown file %d", *idx.File)
+				}
+				c.Files[i].Tracks[j].Indices[k].File = c.Files[*idx.File]
+			}
+		}
+	}
+
+	c.Relink()
+	return nil
+}
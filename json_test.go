@@ -0,0 +1,235 @@
+package gocue
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCuesheet_JSONRoundTrip checks the JSON layout and that decoded sheets keep working.
+func TestCuesheet_JSONRoundTrip(t *testing.T) {
+	cue := `
+PERFORMER "Massive Attack"
+TITLE "Mezzanine"
+FILE "album.flac" WAVE
+  TRACK 01 AUDIO
+    TITLE "Angel"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Risingson"
+    PREGAP 00:02:00
+    INDEX 01 06:19:35
+`
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned an unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"title":"Mezzanine"`,
+		`"time":{"time":"06:19:35","frames":28460}`,
+		`"pregap":{"time":"00:02:00","frames":150}`,
+		`"end":{"time":"06:19:35","frames":28460}`,
+		`"duration":{"time":"06:19:35","frames":28460}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("JSON does not contain %s:\n%s", want, data)
+		}
+	}
+	if strings.Contains(string(data), `"postgap"`) {
+		t.Errorf("JSON contains a zero postgap:\n%s", data)
+	}
+
+	var decoded Cuesheet
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() returned an unexpected error: %v", err)
+	}
+	track1 := decoded.Files[0].Tracks[0]
+	if track1.Duration() != sheet.Files[0].Tracks[0].Duration() || track1.Duration() == 0 {
+		t.Errorf("decoded track 1 duration got %v, want %v", track1.Duration(), sheet.Files[0].Tracks[0].Duration())
+	}
+	if decoded.Files[0].Tracks[1].Pregap.String() != "00:02:00" {
+		t.Errorf("decoded pregap got %s, want 00:02:00", decoded.Files[0].Tracks[1].Pregap)
+	}
+}
+
+// TestTimecode_UnmarshalJSONForms checks the accepted JSON forms of a timecode.
+func TestTimecode_UnmarshalJSONForms(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `"01:02:03"`, want: "01:02:03"},
+		{input: `{"time":"01:02:03","frames":4653}`, want: "01:02:03"},
+		{input: `{"frames":4653}`, want: "01:02:03"},
+		{input: `"01:02"`, wantErr: true},
+		{input: `{"frames":-1}`, wantErr: true},
+	}
+	for _, tc := range testCases {
+		var got Timecode
+		err := json.Unmarshal([]byte(tc.input), &got)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("json.Unmarshal(%s) error = %v, wantErr %v", tc.input, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && got.String() != tc.want {
+			t.Errorf("json.Unmarshal(%s) got %s, want %s", tc.input, got, tc.want)
+		}
+	}
+}
+
+// TestCuesheet_JSONIndexFile checks that indices living in another FILE survive a JSON round trip.
+func TestCuesheet_JSONIndexFile(t *testing.T) {
+	cue := "FILE \"01.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00\nTRACK 02 AUDIO\nINDEX 00 04:10:20\nFILE \"02.wav\" WAVE\nINDEX 01 00:00:00"
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	data, err := json.Marshal(sheet)
+	if err != nil {
+		t.Fatalf("json.Marshal() returned an unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"file":0`) {
+		t.Errorf("JSON does not reference the previous file:\n%s", data)
+	}
+
+	var decoded Cuesheet
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() returned an unexpected error: %v", err)
+	}
+	track2 := decoded.Files[1].Tracks[0]
+	if track2.IndexFile(track2.Indices[0]) != decoded.Files[0] || track2.IndexFile(track2.Indices[1]) != decoded.Files[1] {
+		t.Error("decoded index files do not match")
+	}
+}
+
+// TestCuesheet_UnmarshalJSONNull checks that null files and tracks are rejected instead of panicking.
+func TestCuesheet_UnmarshalJSONNull(t *testing.T) {
+	testCases := []string{
+		`{"files":[null]}`,
+		`{"files":[{"tracks":[null]}]}`,
+	}
+	for _, input := range testCases {
+		var c Cuesheet
+		if err := json.Unmarshal([]byte(input), &c); err == nil {
+			t.Errorf("json.Unmarshal(%s) expected an error, got nil", input)
+		}
+	}
+}
+
+// TestCuesheet_JSONSchema checks marshaled sheets, including an empty one, against the JSON Schema.
+func TestCuesheet_JSONSchema(t *testing.T) {
+	raw, err := os.ReadFile("schema/cuesheet.schema.json")
+	if err != nil {
+		t.Fatalf("cannot read schema: %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		t.Fatalf("cannot decode schema: %v", err)
+	}
+
+	parsed, err := Parse(strings.NewReader("REM DATE 1998\nFILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:00:00"))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	empty := NewCuesheet()
+	empty.AddFile("a.wav", "WAVE")
+	testCases := []struct {
+		name  string
+		sheet *Cuesheet
+	}{
+		{"empty sheet", NewCuesheet()},
+		{"file without tracks", empty},
+		{"parsed sheet", parsed},
+	}
+	for _, tc := range testCases {
+		data, err := json.Marshal(tc.sheet)
+		if err != nil {
+			t.Fatalf("%s: json.Marshal() returned an unexpected error: %v", tc.name, err)
+		}
+		var value any
+		if err := json.Unmarshal(data, &value); err != nil {
+			t.Fatalf("%s: cannot decode JSON: %v", tc.name, err)
+		}
+		if err := validateSchema(schema, schema, value, "$"); err != nil {
+			t.Errorf("%s: %v\n%s", tc.name, err, data)
+		}
+	}
+}
+
+// validateSchema проверяет value по подмножеству JSON Schema, которое
+// использует cuesheet.schema.json: type, enum, properties, required, items и $ref.
+func validateSchema(root, schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		def, _ := root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+		if def == nil {
+			return fmt.Errorf("%s: unknown $ref %s", path, ref)
+		}
+		return validateSchema(root, def, value, path)
+	}
+	if typ, ok := schema["type"]; ok {
+		types, ok := typ.([]any)
+		if !ok {
+			types = []any{typ}
+		}
+		if !slices.ContainsFunc(types, func(t any) bool { return jsonTypeIs(value, t.(string)) }) {
+			return fmt.Errorf("%s: %v does not match type %v", path, value, typ)
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := v[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, field := range v {
+			if prop, ok := props[name].(map[string]any); ok {
+				if err := validateSchema(root, prop, field, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(root, items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonTypeIs сообщает, соответствует ли декодированное значение типу JSON Schema.
+func jsonTypeIs(value any, typ string) bool {
+	switch v := value.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case string:
+		return typ == "string"
+	case float64:
+		return typ == "number" || typ == "integer" && v == float64(int64(v))
+	case []any:
+		return typ == "array"
+	case map[string]any:
+		return typ == "object"
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+)
+
+// Layout описывает, как аудио диска разложено по файлам в CUE sheet.
+// Программы вроде EAC и XLD пишут для одного и того же диска разные раскладки.
+type Layout int
+
+const (
+	// LayoutUnknown — раскладку не удалось определить.
+	LayoutUnknown Layout = iota
+	// LayoutSingleFile — весь диск в одном файле-образе.
+	LayoutSingleFile
+	// LayoutGapsAppended — файл на трек, пауза перед треком (INDEX 00)
+	// дописана в конец файла предыдущего трека и в CUE не размечена.
+	LayoutGapsAppended
+	// LayoutGapsPrepended — файл на трек, каждый файл начинается с паузы
+	// своего трека: INDEX 00 в 00:00:00, INDEX 01 после паузы.
+	LayoutGapsPrepended
+	// LayoutNonCompliant — как LayoutGapsAppended, но INDEX 00 трека записан
+	// в блоке FILE предыдущего трека, а INDEX 01 — в блоке его собственного файла
+	// (см. Index.File).
+	LayoutNonCompliant
+)
+
+// String возвращает название раскладки.
+func (l Layout) String() string {
+	switch l {
+	case LayoutSingleFile:
+		return "single file"
+	case LayoutGapsAppended:
+		return "gaps appended"
+	case LayoutGapsPrepended:
+		return "gaps prepended"
+	case LayoutNonCompliant:
+		return "non-compliant"
+	}
+	return "unknown"
+}
+
+// ErrLayoutUnsupported возвращается, если раскладку нельзя прочитать или записать.
+var ErrLayoutUnsupported = errors.New("unsupported layout")
+
+// LengthFunc возвращает длительность аудиофайла, на который ссылается File.
+// Длительности нужны, чтобы перевести позиции в нескольких файлах на общую шкалу диска.
+type LengthFunc func(f *File) (Timecode, error)
+
+// ConvertOptions задает параметры ConvertLayout.
+type ConvertOptions struct {
+	// Length возвращает длительности исходных файлов. Обязателен, если
+	// в исходном CUE sheet больше одного файла.
+	Length LengthFunc
+
+	// ImageName — имя файла-образа для LayoutSingleFile.
+	// По умолчанию "CDImage" с расширением первого исходного файла.
+	ImageName string
+
+	// TrackFileName возвращает имя файла для трека в раскладках "файл на трек".
+	// По умолчанию используется номер трека с расширением первого исходного файла.
+	TrackFileName func(t *Track) string
+
+	// HiddenTrack включает вывод скрытого трека (HTOA, см. Cuesheet.HiddenTrack)
+	// отдельным файлом с TRACK 00 в раскладках "файл на трек". Без этого флага
+	// скрытое аудио остается в начале файла первого трека. На раскладку
+	// LayoutSingleFile флаг не влияет: там скрытое аудио и так лежит в образе.
+	HiddenTrack bool
+}
+
+// DetectLayout определяет раскладку CUE sheet по структуре блоков FILE и INDEX.
+// Если паузы между треками отсутствуют, "файл на трек" определяется как
+// LayoutGapsAppended: в этом случае раскладки неотличимы.
+func DetectLayout(c *Cuesheet) Layout {
+	if len(c.Files) == 1 {
+		return LayoutSingleFile
+	}
+	if len(c.Files) == 0 {
+		return LayoutUnknown
+	}
+
+	prepended := false
+	for _, f := range c.Files {
+		if len(f.Tracks) != 1 {
+			return LayoutUnknown
+		}
+		t := f.Tracks[0]
+		for _, idx := range t.Indices {
+			if t.IndexFile(idx) != f {
+				return LayoutNonCompliant
+			}
+		}
+		if pregap, ok := t.Index(0); ok && pregap.Time == (Timecode{}) && t.StartTime().After(pregap.Time) {
+			prepended = true
+		}
+	}
+	if prepended {
+		return LayoutGapsPrepended
+	}
+	return LayoutGapsAppended
+}
+
+// ConvertLayout переписывает структуру FILE/INDEX CUE sheet в раскладку target
+// и возвращает новый CUE sheet; исходный не изменяется. Метаданные треков
+// и диска копируются без изменений. Нераспознанные команды и значения
+// обработчиков исходного FILE (Extensions и Ext) переходят в первый файл,
+// построенный из него, а при сборке в один образ — объединяются.
+// Точные таймкоды (Index.Precise) округляются до фрейма.
+//
+// При чтении раскладки LayoutGapsAppended позиции пауз неизвестны, поэтому
+// в результате они не будут размечены INDEX 00, но аудио не потеряется.
+func ConvertLayout(c *Cuesheet, target Layout, opts ConvertOptions) (*Cuesheet, error) {
+	switch target {
+	case LayoutSingleFile, LayoutGapsAppended, LayoutGapsPrepended, LayoutNonCompliant:
+	default:
+		return nil, fmt.Errorf("%w: cannot write %s layout", ErrLayoutUnsupported, target)
+	}
+	if len(c.Files) == 0 {
+		return nil, errors.New("cuesheet has no files")
+	}
+
+	emitHidden := opts.HiddenTrack && target != LayoutSingleFile && c.HiddenTrack() != nil
+	out := c.Clone()
+	tracks, err := absoluteTracks(out, opts.Length)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return nil, errors.New("cuesheet has no tracks")
+	}
+
+	fileType := c.Files[0].Type
+	ext := filepath.Ext(c.Files[0].Name)
+	sources := out.Files
+	source := make(map[*Track]*File, len(tracks)) // Исходный файл трека.
+	for _, f := range sources {
+		for _, t := range f.Tracks {
+			source[t] = f
+		}
+	}
+	out.Files = nil
+
+	if target == LayoutSingleFile {
+		name := opts.ImageName
+		if name == "" {
+			name = "CDImage" + ext
+		}
+		file := out.AddFile(name, fileType)
+		file.Tracks = tracks
+		for _, src := range sources {
+			file.Extensions = append(file.Extensions, src.Extensions...)
+			if len(src.Ext) > 0 {
+				if file.Ext == nil {
+					file.Ext = make(map[string]any, len(src.Ext))
+				}
+				maps.Copy(file.Ext, src.Ext)
+			}
+		}
+		out.Relink()
+		return out, nil
+	}
+
+	trackName := opts.TrackFileName
+	if trackName == nil {
+		trackName = func(t *Track) string { return fmt.Sprintf("%02d%s", t.Number, ext) }
+	}
+	if emitHidden {
+		// После absoluteTracks INDEX 00 первого трека уже стоит в начале диска.
+		hidden := &Track{Type: tracks[0].Type, Flags: slices.Clone(tracks[0].Flags), Indices: []Index{{Number: 1}}}
+		source[hidden] = source[tracks[0]]
+		tracks = append([]*Track{hidden}, tracks...)
+	}
+	copied := make(map[*File]bool, len(sources))
+
+	var prevFile *File
+	prevStart := 0
+	for i, t := range tracks {
+		// Начало файла трека: для первого трека — начало диска, иначе INDEX 00
+		// (паузы в начале файлов) или INDEX 01 (паузы в конце предыдущих файлов).
+		// Если скрытый трек выводится отдельно, его пауза не входит в трек 01.
+		start := 0
+		if i > 0 {
+			start = t.StartTime().TotalFrames()
+			if pregap, ok := t.Index(0); ok && target == LayoutGapsPrepended && !(emitHidden && i == 1) {
+				start = pregap.Time.TotalFrames()
+			}
+		}
+
+		file := out.AddFile(trackName(t), fileType)
+		if src := source[t]; src != nil && !copied[src] {
+			file.Extensions, file.Ext = src.Extensions, src.Ext
+			copied[src] = true
+		}
+		var indices []Index
+		for _, idx := range t.Indices {
+			if emitHidden && i == 1 && idx.Number == 0 {
+				continue // Пауза трека 01 — это скрытый трек, уже выведенный отдельно.
+			}
+			frames := idx.Time.TotalFrames() - start
+			if frames < 0 {
+				// Пауза осталась в файле предыдущего трека. В несовместимой
+				// раскладке она размечается там же, в остальных теряется.
+				if target == LayoutNonCompliant && prevFile != nil {
+					indices = append(indices, Index{
+						Number: idx.Number,
+						Time:   NewTimecodeFromFrames(idx.Time.TotalFrames() - prevStart),
+						File:   prevFile,
+					})
+				}
+				continue
+			}
+			indices = append(indices, Index{Number: idx.Number, Time: NewTimecodeFromFrames(frames)})
+		}
+		t.Indices = indices
+		file.Tracks = []*Track{t}
+		prevFile, prevStart = file, start
+	}
+	out.Relink()
+	return out, nil
+}
+
+// absoluteTracks возвращает все треки CUE sheet, переведя их индексы на общую
+// шкалу диска. Для этого позиции в каждом файле сдвигаются на суммарную
+// длительность предыдущих файлов.
+func absoluteTracks(c *Cuesheet, length LengthFunc) ([]*Track, error) {
+	if len(c.Files) > 1 && length == nil {
+		return nil, errors.New("file lengths are required to convert a multi-file cuesheet")
+	}
+
+	offsets := make(map[*File]int, len(c.Files))
+	offset := 0
+	for i, f := range c.Files {
+		offsets[f] = offset
+		if i < len(c.Files)-1 {
+			fileLength, err := length(f)
+			if err != nil {
+				return nil, fmt.Errorf("length of file %q: %w", f.Name, err)
+			}
+			offset += fileLength.TotalFrames()
+		}
+	}
+
+	var tracks []*Track
+	last := -1
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			for j, idx := range t.Indices {
+				// Индекс может лежать в другом файле, чем сам трек (см. Index.File).
+				frames := offsets[t.IndexFile(idx)] + idx.Time.TotalFrames()
+				if frames < last {
+					return nil, fmt.Errorf("track %d: index %02d goes back in time", t.Number, idx.Number)
+				}
+				last = frames
+				t.Indices[j] = Index{Number: idx.Number, Time: NewTimecodeFromFrames(frames)}
+			}
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks, nil
+}
@@ -0,0 +1,139 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const layoutImageCue = `
+FILE "CDImage.flac" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:58:00
+    INDEX 01 04:00:00
+  TRACK 03 AUDIO
+    INDEX 00 07:59:00
+    INDEX 01 08:00:00
+    INDEX 02 09:00:00
+`
+
+// layoutSummary описывает файлы и индексы как "файл[номер трека: индексы]" для сравнения в тестах.
+func layoutSummary(sheet *Cuesheet) string {
+	var files []string
+	for _, f := range sheet.Files {
+		var tracks []string
+		for _, t := range f.Tracks {
+			var indices []string
+			for _, idx := range t.Indices {
+				indices = append(indices, fmt.Sprintf("%02d@%s", idx.Number, idx.Time))
+			}
+			tracks = append(tracks, fmt.Sprintf("%02d: %s", t.Number, strings.Join(indices, " ")))
+		}
+		files = append(files, fmt.Sprintf("%s[%s]", f.Name, strings.Join(tracks, "; ")))
+	}
+	return strings.Join(files, " ")
+}
+
+// TestConvertLayout checks conversions from a single image to per-track layouts and back.
+func TestConvertLayout(t *testing.T) {
+	image, err := Parse(strings.NewReader(layoutImageCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	original := layoutSummary(image)
+	if got := DetectLayout(image); got != LayoutSingleFile {
+		t.Errorf("DetectLayout() got %s, want %s", got, LayoutSingleFile)
+	}
+
+	prepended, err := ConvertLayout(image, LayoutGapsPrepended, ConvertOptions{})
+	if err != nil {
+		t.Fatalf("ConvertLayout(prepended) returned an unexpected error: %v", err)
+	}
+	want := "01.flac[01: 01@00:00:00] 02.flac[02: 00@00:00:00 01@00:02:00] 03.flac[03: 00@00:00:00 01@00:01:00 02@01:01:00]"
+	if got := layoutSummary(prepended); got != want {
+		t.Errorf("prepended layout:\ngot  %s\nwant %s", got, want)
+	}
+	if got := DetectLayout(prepended); got != LayoutGapsPrepended {
+		t.Errorf("DetectLayout() got %s, want %s", got, LayoutGapsPrepended)
+	}
+
+	appended, err := ConvertLayout(image, LayoutGapsAppended, ConvertOptions{})
+	if err != nil {
+		t.Fatalf("ConvertLayout(appended) returned an unexpected error: %v", err)
+	}
+	want = "01.flac[01: 01@00:00:00] 02.flac[02: 01@00:00:00] 03.flac[03: 01@00:00:00 02@01:00:00]"
+	if got := layoutSummary(appended); got != want {
+		t.Errorf("appended layout:\ngot  %s\nwant %s", got, want)
+	}
+
+	// Обратно в образ: длительности файлов трека берем из исходного образа.
+	lengths := map[string]Timecode{
+		"01.flac": {Minutes: 3, Seconds: 58},
+		"02.flac": {Minutes: 4, Seconds: 1},
+	}
+	back, err := ConvertLayout(prepended, LayoutSingleFile, ConvertOptions{
+		ImageName: "CDImage.flac",
+		Length:    func(f *File) (Timecode, error) { return lengths[f.Name], nil },
+	})
+	if err != nil {
+		t.Fatalf("ConvertLayout(single) returned an unexpected error: %v", err)
+	}
+	if got := layoutSummary(back); got != original {
+		t.Errorf("round trip:\ngot  %s\nwant %s", got, original)
+	}
+	if layoutSummary(image) != original {
+		t.Error("ConvertLayout() modified its input")
+	}
+}
+
+// TestConvertLayout_Errors checks that missing lengths and unsupported targets are reported.
+func TestConvertLayout_Errors(t *testing.T) {
+	image, _ := Parse(strings.NewReader(layoutImageCue))
+	perTrack, _ := ConvertLayout(image, LayoutGapsAppended, ConvertOptions{})
+
+	if _, err := ConvertLayout(perTrack, LayoutSingleFile, ConvertOptions{}); err == nil {
+		t.Error("ConvertLayout() without lengths did not return an error")
+	}
+	failing := func(*File) (Timecode, error) { return Timecode{}, errors.New("no such file") }
+	if _, err := ConvertLayout(perTrack, LayoutSingleFile, ConvertOptions{Length: failing}); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Errorf("ConvertLayout() got error %v, want the length provider error", err)
+	}
+	if _, err := ConvertLayout(image, LayoutUnknown, ConvertOptions{}); !errors.Is(err, ErrLayoutUnsupported) {
+		t.Errorf("ConvertLayout(unknown) got error %v, want ErrLayoutUnsupported", err)
+	}
+}
+
+// TestConvertLayout_NonCompliant checks reading and writing indices that live in the previous FILE.
+func TestConvertLayout_NonCompliant(t *testing.T) {
+	image, _ := Parse(strings.NewReader(layoutImageCue))
+	nonCompliant, err := ConvertLayout(image, LayoutNonCompliant, ConvertOptions{})
+	if err != nil {
+		t.Fatalf("ConvertLayout(non-compliant) returned an unexpected error: %v", err)
+	}
+	if got := DetectLayout(nonCompliant); got != LayoutNonCompliant {
+		t.Errorf("DetectLayout() got %s, want %s", got, LayoutNonCompliant)
+	}
+	track2 := nonCompliant.Files[1].Tracks[0]
+	pregap := track2.Indices[0]
+	if pregap.Number != 0 || pregap.Time.String() != "03:58:00" || track2.IndexFile(pregap) != nonCompliant.Files[0] {
+		t.Errorf("track 2 INDEX 00 got %s in %v, want 03:58:00 in 01.flac", pregap.Time, track2.IndexFile(pregap).Name)
+	}
+
+	lengths := map[string]Timecode{
+		"01.flac": {Minutes: 4},
+		"02.flac": {Minutes: 4},
+	}
+	back, err := ConvertLayout(nonCompliant, LayoutSingleFile, ConvertOptions{
+		ImageName: "CDImage.flac",
+		Length:    func(f *File) (Timecode, error) { return lengths[f.Name], nil },
+	})
+	if err != nil {
+		t.Fatalf("ConvertLayout(single) returned an unexpected error: %v", err)
+	}
+	if got, want := layoutSummary(back), layoutSummary(image); got != want {
+		t.Errorf("round trip:\ngot  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,218 @@
+package gocue
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"io"
+)
+
+// LineEnding — стиль перевода строки. Значение — сами символы перевода строки.
+type LineEnding string
+
+// Стили перевода строки.
+const (
+	LineEndingLF   LineEnding = "\n"   // Unix и современный macOS.
+	LineEndingCRLF LineEnding = "\r\n" // Windows.
+	LineEndingCR   LineEnding = "\r"   // Классический Mac OS.
+)
+
+// String возвращает название стиля: "LF", "CRLF" или "CR".
+func (e LineEnding) String() string {
+	switch e {
+	case LineEndingLF:
+		return "LF"
+	case LineEndingCRLF:
+		return "CRLF"
+	case LineEndingCR:
+		return "CR"
+	}
+	return fmt.Sprintf("%q", string(e))
+}
+
+// Warning — предупреждение о строке, которую декодер исправил при чтении.
+type Warning struct {
+	Line int // Номер строки, начиная с 1.
+	Msg  string
+}
+
+// String возвращает текст предупреждения.
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Msg)
+}
+
+// lineEndings — стили в порядке счетчиков Decoder.endings.
+var lineEndings = [...]LineEnding{LineEndingLF, LineEndingCRLF, LineEndingCR}
+
+// utf8BOM — метка порядка байтов UTF-8.
+var utf8BOM = []byte("\uFEFF")
+
+// minBufSize — начальный размер буфера чтения.
+const minBufSize = 4096
+
+// LineEnding возвращает стиль перевода строки прочитанных данных: самый
+// частый, а при равенстве — встреченный первым. Пустая строка означает,
+// что переводов строки еще не было.
+func (d *Decoder) LineEnding() LineEnding {
+	best := d.first
+	for i, n := range d.endings {
+		if n > d.endings[endingIndex(best)] {
+			best = lineEndings[i]
+		}
+	}
+	return best
+}
+
+// endingIndex возвращает индекс счетчика стиля e.
+func endingIndex(e LineEnding) int {
+	switch e {
+	case LineEndingCRLF:
+		return 1
+	case LineEndingCR:
+		return 2
+	}
+	return 0
+}
+
+// countEnding учитывает перевод строки текущей строки и предупреждает,
+// когда стиль меняется.
+func (d *Decoder) countEnding(e LineEnding) {
+	if e == "" {
+		return // Последняя строка без перевода строки.
+	}
+	d.endings[endingIndex(e)]++
+	switch {
+	case d.first == "":
+		d.first = e
+	case e != d.first && d.endings[endingIndex(e)] == 1:
+		d.warn("mixed line endings: %v after %v", e, d.first)
+	}
+}
+
+// warn передает предупреждение о текущей строке в OnWarning.
+func (d *Decoder) warn(format string, args ...any) {
+	if d.OnWarning != nil {
+		d.OnWarning(Warning{Line: d.line, Msg: fmt.Sprintf(format, args...)})
+	}
+}
+
+// readLine читает строку до перевода строки \n, \r\n или \r и возвращает ее
+// без него вместе со стилем перевода (пустым для последней строки без него).
+// Строка действительна до следующего вызова. В конце данных возвращается io.EOF.
+func (d *Decoder) readLine() ([]byte, LineEnding, error) {
+	limit := cmp.Or(d.MaxLineLength, DefaultMaxLineLength)
+	scanned := 0 // Сколько байт строки уже просмотрено.
+	for {
+		data := d.buf[d.start:d.end]
+		i := indexEOL(data[scanned:])
+		if i >= 0 {
+			i += scanned
+			// После \r в конце буфера может прийти \n: дочитываем.
+			if data[i] == '\n' || i+1 < len(data) || d.err != nil {
+				ending, n := LineEndingLF, 1
+				if data[i] == '\r' {
+					ending = LineEndingCR
+					if i+1 < len(data) && data[i+1] == '\n' {
+						ending, n = LineEndingCRLF, 2
+					}
+				}
+				if i > limit {
+					return nil, "", fmt.Errorf("%w: limit is %d bytes", ErrLineTooLong, limit)
+				}
+				d.start += i + n
+				return data[:i], ending, nil
+			}
+			scanned = i
+		} else {
+			scanned = len(data)
+			if d.err != nil {
+				if len(data) == 0 || d.err != io.EOF {
+					return nil, "", d.err
+				}
+				d.start = d.end
+				if len(data) > limit {
+					return nil, "", fmt.Errorf("%w: limit is %d bytes", ErrLineTooLong, limit)
+				}
+				return data, "", nil // Последняя строка без перевода строки.
+			}
+			if len(data) > limit {
+				return nil, "", fmt.Errorf("%w: limit is %d bytes", ErrLineTooLong, limit)
+			}
+		}
+		d.fill()
+	}
+}
+
+// indexEOL возвращает позицию первого \n или \r в data либо -1.
+func indexEOL(data []byte) int {
+	i := bytes.IndexByte(data, '\n')
+	if i >= 0 {
+		data = data[:i]
+	}
+	if j := bytes.IndexByte(data, '\r'); j >= 0 {
+		return j
+	}
+	return i
+}
+
+// fill дочитывает данные из источника, при необходимости сдвигая
+// и увеличивая буфер.
+func (d *Decoder) fill() {
+	if d.start > 0 {
+		d.end = copy(d.buf, d.buf[d.start:d.end])
+		d.start = 0
+	}
+	if d.end == len(d.buf) {
+		buf := make([]byte, max(2*len(d.buf), minBufSize))
+		copy(buf, d.buf[:d.end])
+		d.buf = buf
+	}
+	n, err := d.src.Read(d.buf[d.end:])
+	d.end += n
+	if err != nil {
+		d.err = err
+	}
+}
+
+// sanitize удаляет из строки метки порядка байтов UTF-8 и управляющие
+// символы, кроме табуляции. Метка в начале данных удаляется молча, остальные
+// исправления сопровождаются предупреждением.
+func (d *Decoder) sanitize(line []byte) []byte {
+	if d.line == 1 {
+		line = bytes.TrimPrefix(line, utf8BOM)
+	}
+	if !needsSanitize(line) {
+		return line
+	}
+
+	d.clean = d.clean[:0]
+	boms, controls := 0, 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == utf8BOM[0] && bytes.HasPrefix(line[i:], utf8BOM):
+			boms++
+			i += len(utf8BOM) - 1
+		case c < 0x20 && c != '\t' || c == 0x7F:
+			controls++
+		default:
+			d.clean = append(d.clean, c)
+		}
+	}
+	if boms > 0 {
+		d.warn("removed %d byte order mark(s)", boms)
+	}
+	if controls > 0 {
+		d.warn("removed %d control character(s)", controls)
+	}
+	return d.clean
+}
+
+// needsSanitize сообщает, есть ли в строке символы, которые удаляет sanitize.
+func needsSanitize(line []byte) bool {
+	for i, c := range line {
+		if c < 0x20 && c != '\t' || c == 0x7F || c == utf8BOM[0] && bytes.HasPrefix(line[i:], utf8BOM) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package gocue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestDecoder_LineEndings checks line splitting and the reported style for
+// every line ending, with the input delivered byte by byte so that a CRLF
+// may be split between reads.
+func TestDecoder_LineEndings(t *testing.T) {
+	testCases := []struct {
+		name         string
+		input        string
+		wantEnding   LineEnding
+		wantWarnings []Warning
+	}{
+		{name: "LF", input: "TITLE \"a\"\nPERFORMER \"b\"\n\nFILE \"c.wav\" WAVE\n", wantEnding: LineEndingLF},
+		{name: "CRLF", input: "TITLE \"a\"\r\nPERFORMER \"b\"\r\n\r\nFILE \"c.wav\" WAVE\r\n", wantEnding: LineEndingCRLF},
+		{name: "CR", input: "TITLE \"a\"\rPERFORMER \"b\"\r\rFILE \"c.wav\" WAVE", wantEnding: LineEndingCR},
+		{
+			name:         "mixed",
+			input:        "TITLE \"a\"\r\nPERFORMER \"b\"\n\nFILE \"c.wav\" WAVE\n",
+			wantEnding:   LineEndingLF,
+			wantWarnings: []Warning{{Line: 2, Msg: "mixed line endings: LF after CRLF"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var warnings []Warning
+			d := NewDecoder(iotest.OneByteReader(strings.NewReader(tc.input)))
+			d.OnWarning = func(w Warning) { warnings = append(warnings, w) }
+
+			var commands []string
+			for {
+				ev, err := d.Next()
+				if err != nil {
+					break
+				}
+				commands = append(commands, ev.Command)
+			}
+			if want := []string{"TITLE", "PERFORMER", "FILE"}; !reflect.DeepEqual(commands, want) {
+				t.Errorf("got commands %v, want %v", commands, want)
+			}
+			if d.LineEnding() != tc.wantEnding {
+				t.Errorf("LineEnding() got %v, want %v", d.LineEnding(), tc.wantEnding)
+			}
+			if !reflect.DeepEqual(warnings, tc.wantWarnings) {
+				t.Errorf("got warnings %v, want %v", warnings, tc.wantWarnings)
+			}
+		})
+	}
+}
+
+// TestParse_DirtyInput checks BOMs and control characters.
+func TestParse_DirtyInput(t *testing.T) {
+	var warnings []string
+	p := &Parser{OnWarning: func(w Warning) { warnings = append(warnings, w.String()) }}
+	sheet, err := p.Parse(strings.NewReader("\uFEFFREM GENRE Jazz\r\nTITLE \"Kind\x00 of Blue\"\x00\r\n\uFEFFPERFORMER \"Miles Davis\"\r\n"))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if genre, _ := sheet.RemValue("GENRE"); genre != "Jazz" || len(sheet.Extensions) != 0 {
+		t.Errorf("BOM on line 1 was not stripped: REM %q, extensions %v", genre, sheet.Extensions)
+	}
+	if sheet.Title != "Kind of Blue" || sheet.Performer != "Miles Davis" {
+		t.Errorf("got title %q, performer %q", sheet.Title, sheet.Performer)
+	}
+	want := []string{"line 2: removed 2 control character(s)", "line 3: removed 1 byte order mark(s)"}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("got warnings %q, want %q", warnings, want)
+	}
+	if sheet.LineEnding != LineEndingCRLF {
+		t.Errorf("LineEnding got %v, want CRLF", sheet.LineEnding)
+	}
+}
+
+// TestWrite_LineEnding checks that the source line ending survives a round trip.
+func TestWrite_LineEnding(t *testing.T) {
+	for _, ending := range []LineEnding{LineEndingLF, LineEndingCRLF, LineEndingCR} {
+		cue := strings.ReplaceAll("TITLE \"a\"\nFILE \"a.wav\" WAVE\n  TRACK 01 AUDIO\n    INDEX 01 00:00:00\n", "\n", string(ending))
+		sheet, err := Parse(strings.NewReader(cue))
+		if err != nil {
+			t.Fatalf("%v: Parse() returned an unexpected error: %v", ending, err)
+		}
+		var b strings.Builder
+		if err := Write(&b, sheet); err != nil {
+			t.Fatalf("%v: Write() returned an unexpected error: %v", ending, err)
+		}
+		if b.String() != cue {
+			t.Errorf("%v: Write() got %q, want %q", ending, b.String(), cue)
+		}
+	}
+}
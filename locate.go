@@ -0,0 +1,104 @@
+package gocue
+
+import (
+	"slices"
+	"time"
+)
+
+// Location описывает, что звучит в заданной позиции файла.
+type Location struct {
+	Track *Track // Трек, которому принадлежит позиция.
+	Index Index  // Последний индекс трека, пройденный к этой позиции.
+
+	// Offset — смещение позиции от INDEX 01 трека. Внутри паузы перед
+	// треком (INDEX 00) оно отрицательное. Если пауза лежит в предыдущем
+	// файле, а INDEX 01 — уже в следующем, расстояние до INDEX 01 без длины
+	// файла неизвестно; тогда Offset отсчитывается от INDEX 00 и неотрицателен,
+	// а распознать паузу можно по Index.Number == 0.
+	Offset time.Duration
+}
+
+// locatorEntry — одна строка таблицы поиска: позиция индекса в файле.
+type locatorEntry struct {
+	frames int // Позиция индекса от начала файла.
+	track  *Track
+	index  Index
+	anchor Index // Индекс, от которого отсчитывается Offset: INDEX 01 или, если он в другом файле, сам индекс.
+}
+
+// Locator отвечает на вопрос "какой трек и индекс звучат в позиции pos файла".
+// Таблица индексов строится один раз при создании, а поиск выполняется
+// двоичным поиском, поэтому Locator подходит для плееров и стримеров, которые
+// часто пересчитывают позицию. После изменения CUE sheet Locator нужно создать заново.
+type Locator struct {
+	files map[*File][]locatorEntry
+}
+
+// NewLocator строит таблицу поиска для всех файлов CUE sheet.
+func NewLocator(c *Cuesheet) *Locator {
+	l := &Locator{files: make(map[*File][]locatorEntry)}
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			start, hasStart := t.Index(1)
+			for _, idx := range t.Indices {
+				file := t.IndexFile(idx)
+				anchor := idx
+				if hasStart && t.IndexFile(start) == file {
+					anchor = start
+				}
+				l.files[file] = append(l.files[file], locatorEntry{
+					frames: idx.Time.TotalFrames(),
+					track:  t,
+					index:  idx,
+					anchor: anchor,
+				})
+			}
+		}
+	}
+	for _, entries := range l.files {
+		// Стабильная сортировка сохраняет порядок индексов с одинаковым временем.
+		slices.SortStableFunc(entries, func(a, b locatorEntry) int {
+			return a.frames - b.frames
+		})
+	}
+	return l
+}
+
+// Locate возвращает трек и индекс, которые звучат в позиции pos файла file.
+// Позиции до первого индекса файла и файлы без треков дают ok == false.
+// Для последнего трека файла конец неизвестен, поэтому все позиции после
+// его начала относятся к нему.
+func (l *Locator) Locate(file *File, pos Timecode) (loc Location, ok bool) {
+	entry, ok := l.find(file, pos.TotalFrames())
+	if !ok {
+		return Location{}, false
+	}
+	offset := time.Duration(pos.TotalFrames()-entry.anchor.Time.TotalFrames()) * time.Second / FramesPerSecond
+	return Location{Track: entry.track, Index: entry.index, Offset: offset}, true
+}
+
+// LocateDuration работает как Locate, но принимает позицию в виде time.Duration.
+// Смещение в результате вычисляется с точностью исходной позиции, а не фрейма.
+func (l *Locator) LocateDuration(file *File, pos time.Duration) (loc Location, ok bool) {
+	if pos < 0 {
+		return Location{}, false
+	}
+	entry, ok := l.find(file, TimecodeFromDuration(pos, RoundFloor).TotalFrames())
+	if !ok {
+		return Location{}, false
+	}
+	return Location{Track: entry.track, Index: entry.index, Offset: pos - entry.anchor.Time.AsDuration()}, true
+}
+
+// find ищет последний индекс файла, позиция которого не больше frames.
+func (l *Locator) find(file *File, frames int) (locatorEntry, bool) {
+	entries := l.files[file]
+	// Первый индекс с позицией больше frames; нужный нам стоит прямо перед ним.
+	i, _ := slices.BinarySearchFunc(entries, frames+1, func(e locatorEntry, target int) int {
+		return e.frames - target
+	})
+	if i == 0 {
+		return locatorEntry{}, false
+	}
+	return entries[i-1], true
+}
@@ -0,0 +1,86 @@
+package gocue
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLocator_Locate checks lookups across pregaps, sub-indices and files.
+func TestLocator_Locate(t *testing.T) {
+	cue := `
+FILE "01.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+    INDEX 02 01:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:58:00
+    INDEX 01 04:00:00
+FILE "02.wav" WAVE
+  TRACK 03 AUDIO
+    INDEX 01 00:00:00
+  TRACK 04 AUDIO
+    INDEX 00 04:10:20
+FILE "03.wav" WAVE
+    INDEX 01 00:00:00
+`
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	locator := NewLocator(sheet)
+	file1, file2, file3 := sheet.Files[0], sheet.Files[1], sheet.Files[2]
+
+	testCases := []struct {
+		name   string
+		file   *File
+		pos    Timecode
+		track  int
+		index  int
+		offset time.Duration
+	}{
+		{"track start", file1, Timecode{}, 1, 1, 0},
+		{"sub-index", file1, Timecode{Minutes: 2}, 1, 2, 2 * time.Minute},
+		{"pregap is negative", file1, Timecode{Minutes: 3, Seconds: 59}, 2, 0, -time.Second},
+		{"exact INDEX 01", file1, Timecode{Minutes: 4}, 2, 1, 0},
+		{"last track of file", file1, Timecode{Minutes: 40}, 2, 1, 36 * time.Minute},
+		{"second file", file2, Timecode{Seconds: 30}, 3, 1, 30 * time.Second},
+		{"pregap in previous file", file2, Timecode{Minutes: 4, Seconds: 11, Frames: 20}, 4, 0, time.Second},
+		{"after pregap file change", file3, Timecode{Seconds: 5}, 4, 1, 5 * time.Second},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, ok := locator.Locate(tc.file, tc.pos)
+			if !ok {
+				t.Fatal("Locate() did not find a track")
+			}
+			if loc.Track.Number != tc.track || loc.Index.Number != tc.index || loc.Offset != tc.offset {
+				t.Errorf("got track %d index %d offset %v, want track %d index %d offset %v",
+					loc.Track.Number, loc.Index.Number, loc.Offset, tc.track, tc.index, tc.offset)
+			}
+		})
+	}
+
+	if _, ok := locator.Locate(&File{}, Timecode{}); ok {
+		t.Error("Locate() found a track in an unknown file")
+	}
+}
+
+// TestLocator_LocateDuration checks the time.Duration variant and positions before the first index.
+func TestLocator_LocateDuration(t *testing.T) {
+	cue := "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 00:02:00\nTRACK 02 AUDIO\nINDEX 00 01:00:00\nINDEX 01 01:02:00"
+	sheet, _ := Parse(strings.NewReader(cue))
+	file := sheet.Files[0]
+	locator := NewLocator(sheet)
+
+	if _, ok := locator.LocateDuration(file, time.Second); ok {
+		t.Error("LocateDuration() found a track before the first index")
+	}
+	loc, ok := locator.LocateDuration(file, time.Minute+500*time.Millisecond)
+	if !ok || loc.Track.Number != 2 || loc.Offset != -1500*time.Millisecond {
+		t.Errorf("LocateDuration() got track %v offset %v ok=%v, want track 2 offset -1.5s", loc.Track, loc.Offset, ok)
+	}
+	if loc, ok := locator.Locate(file, Timecode{Seconds: 59, Frames: 74}); !ok || loc.Track.Number != 1 {
+		t.Errorf("Locate() just before the pregap got %v ok=%v, want track 1", loc.Track, ok)
+	}
+}
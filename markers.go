@@ -0,0 +1,73 @@
+package gocue
+
+// Marker описывает отметку внутри трека: INDEX 01 или один из индексов 02-99,
+// которыми на классических и концертных альбомах размечают части произведения.
+type Marker struct {
+	Index  Index    // Исходный индекс.
+	Offset Timecode // Смещение от INDEX 01 трека.
+	Length Timecode // Длина до следующего индекса или до конца трека; нулевая, если конец неизвестен.
+}
+
+// Index возвращает индекс трека с указанным номером.
+// Если такого индекса нет, ok будет равен false.
+func (t *Track) Index(number int) (idx Index, ok bool) {
+	for _, idx := range t.Indices {
+		if idx.Number == number {
+			return idx, true
+		}
+	}
+	return Index{}, false
+}
+
+// Markers возвращает отметки трека начиная с INDEX 01: смещение каждой
+// относительно INDEX 01 и длину до следующей отметки. Длина последней
+// отметки считается до начала следующего трека в том же файле, а если
+// конец трека неизвестен (см. Duration), она будет нулевой.
+// Если у трека нет INDEX 01, возвращается nil.
+func (t *Track) Markers() []Marker {
+	start, ok := t.Index(1)
+	if !ok {
+		return nil
+	}
+
+	var markers []Marker
+	for _, idx := range t.Indices {
+		if idx.Number < 1 || t.IndexFile(idx) != t.IndexFile(start) {
+			continue
+		}
+		markers = append(markers, Marker{Index: idx, Offset: idx.Time.Sub(start.Time)})
+	}
+
+	var next *Track
+	if t.parentFile != nil {
+		next = t.parentFile.findNextTrack(t)
+	}
+	for i := range markers {
+		var end Timecode
+		if i+1 < len(markers) {
+			end = markers[i+1].Index.Time
+		} else if next != nil {
+			end = next.StartTime()
+		} else {
+			continue // Конец трека неизвестен.
+		}
+		markers[i].Length = end.Sub(markers[i].Index.Time)
+	}
+	return markers
+}
+
+// PregapLength возвращает длину паузы перед треком — расстояние от INDEX 00
+// до INDEX 01. Длительность тишины из команды PREGAP сюда не входит, она
+// хранится в поле Pregap. Если INDEX 00 нет или он лежит в другом файле,
+// чем INDEX 01 (длина предыдущего файла неизвестна), возвращается нулевой таймкод.
+func (t *Track) PregapLength() Timecode {
+	pregap, ok := t.Index(0)
+	if !ok {
+		return Timecode{}
+	}
+	start, ok := t.Index(1)
+	if !ok || t.IndexFile(pregap) != t.IndexFile(start) {
+		return Timecode{}
+	}
+	return start.Time.Sub(pregap.Time)
+}
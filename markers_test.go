@@ -0,0 +1,59 @@
+package gocue
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTrack_Markers checks sub-index offsets, lengths and the pregap length.
+func TestTrack_Markers(t *testing.T) {
+	cue := `
+FILE "symphony.flac" WAVE
+  TRACK 01 AUDIO
+    INDEX 00 00:00:00
+    INDEX 01 00:02:00
+    INDEX 02 10:02:00
+    INDEX 03 18:32:40
+  TRACK 02 AUDIO
+    INDEX 01 25:00:00
+`
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	track1, track2 := sheet.Files[0].Tracks[0], sheet.Files[0].Tracks[1]
+
+	if idx, ok := track1.Index(2); !ok || idx.Time.String() != "10:02:00" {
+		t.Errorf("Index(2) got %s ok=%v, want 10:02:00", idx.Time, ok)
+	}
+	if _, ok := track1.Index(4); ok {
+		t.Error("Index(4) reported a missing index")
+	}
+
+	want := []struct{ offset, length string }{
+		{"00:00:00", "10:00:00"},
+		{"10:00:00", "08:30:40"},
+		{"18:30:40", "06:27:35"},
+	}
+	markers := track1.Markers()
+	if len(markers) != len(want) {
+		t.Fatalf("got %d markers, want %d", len(markers), len(want))
+	}
+	for i, w := range want {
+		if markers[i].Offset.String() != w.offset || markers[i].Length.String() != w.length {
+			t.Errorf("marker %d got offset %s length %s, want %s %s", i, markers[i].Offset, markers[i].Length, w.offset, w.length)
+		}
+	}
+
+	last := track2.Markers()
+	if len(last) != 1 || last[0].Length != (Timecode{}) {
+		t.Errorf("last track markers got %+v, want one marker with unknown length", last)
+	}
+
+	if got := track1.PregapLength().String(); got != "00:02:00" {
+		t.Errorf("PregapLength() got %s, want 00:02:00", got)
+	}
+	if got := track2.PregapLength(); got != (Timecode{}) {
+		t.Errorf("PregapLength() without INDEX 00 got %s, want 00:00:00", got)
+	}
+}
@@ -0,0 +1,180 @@
+package gocue
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// VariousArtists — исполнитель альбома, который подставляется для сборников
+// без PERFORMER на уровне диска.
+const VariousArtists = "Various Artists"
+
+// Metadata — итоговые метаданные трека после применения правил наследования
+// (см. Track.Effective). Пустая строка или ноль означают, что значение не задано.
+type Metadata struct {
+	Title       string
+	Artist      string
+	AlbumArtist string
+	Album       string
+	Composer    string
+	Genre       string
+	Date        string
+	Comment     string
+	ISRC        string
+	TrackNumber int
+	TotalTracks int
+	DiscNumber  int
+	TotalDiscs  int
+	Compilation bool // Диск — сборник разных исполнителей.
+}
+
+// Effective возвращает метаданные трека, дополненные данными диска:
+//
+//   - Title и ISRC берутся только из трека; Album — из TITLE диска.
+//   - Artist — PERFORMER трека, а если его нет — PERFORMER диска.
+//   - AlbumArtist — PERFORMER диска. Если его нет, для сборника это
+//     VariousArtists, иначе общий PERFORMER всех треков.
+//   - Composer — первое заданное из COMPOSER и SONGWRITER трека, затем
+//     COMPOSER и SONGWRITER диска.
+//   - Genre — REM GENRE трека, затем GENRE и REM GENRE диска.
+//   - Date и Comment — из REM DATE и REM COMMENT трека, а если их нет —
+//     из одноименных REM диска.
+//   - DiscNumber и TotalDiscs — из REM DISCNUMBER (допускается вид "1/2")
+//     и REM TOTALDISCS.
+//   - TotalTracks — число треков в CUE sheet.
+//   - Compilation — true, если задан REM COMPILATION с истинным значением,
+//     если PERFORMER диска равен "Various Artists" или "VA", либо если
+//     PERFORMER хотя бы одного трека отличается от PERFORMER диска
+//     (без PERFORMER диска — если треки исполняют разные исполнители).
+//
+// Для трека, не привязанного к CUE sheet, заполняются только поля самого трека.
+func (t *Track) Effective() Metadata {
+	m := Metadata{
+		Title:       t.Title,
+		Artist:      t.Performer,
+		Composer:    cmp.Or(t.Composer, t.Songwriter),
+		ISRC:        t.ISRC,
+		TrackNumber: t.Number,
+	}
+	var c *Cuesheet
+	if t.parentFile != nil {
+		c = t.parentFile.parentSheet
+	}
+	if c == nil {
+		m.Genre, _ = t.RemValue("GENRE")
+		m.Date, _ = t.RemValue("DATE")
+		m.Comment, _ = t.RemValue("COMMENT")
+		return m
+	}
+
+	m.Album = c.Title
+	m.Artist = cmp.Or(m.Artist, c.Performer)
+	m.Composer = cmp.Or(m.Composer, c.Composer, c.Songwriter)
+	m.Genre, _ = t.RemValue("GENRE")
+	if m.Genre == "" {
+		remGenre, _ := c.RemValue("GENRE")
+		m.Genre = cmp.Or(c.Genre, remGenre)
+	}
+	m.Date = inheritedRem(t, c, "DATE")
+	m.Comment = inheritedRem(t, c, "COMMENT")
+	m.TotalTracks = c.trackCount()
+
+	if v, ok := c.RemValue("DISCNUMBER"); ok {
+		disc, total, _ := strings.Cut(v, "/")
+		m.DiscNumber, _ = strconv.Atoi(strings.TrimSpace(disc))
+		m.TotalDiscs, _ = strconv.Atoi(strings.TrimSpace(total))
+	}
+	if v, ok := c.RemValue("TOTALDISCS"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			m.TotalDiscs = n
+		}
+	}
+
+	shared, compilation := c.sharedPerformer()
+	m.Compilation = compilation
+	switch {
+	case c.Performer != "":
+		m.AlbumArtist = c.Performer
+	case compilation:
+		m.AlbumArtist = VariousArtists
+	default:
+		m.AlbumArtist = shared
+	}
+	return m
+}
+
+// sharedPerformer возвращает общий PERFORMER треков и сообщает, является ли
+// диск сборником по правилам, описанным в Track.Effective.
+func (c *Cuesheet) sharedPerformer() (shared string, compilation bool) {
+	if v, ok := c.RemValue("COMPILATION"); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return "", b
+		}
+	}
+	if strings.EqualFold(c.Performer, VariousArtists) || strings.EqualFold(c.Performer, "VA") {
+		return "", true
+	}
+
+	for _, t := range c.Tracks() {
+		switch {
+		case t.Performer == "":
+		case c.Performer != "" && !strings.EqualFold(t.Performer, c.Performer):
+			return "", true
+		case shared == "":
+			shared = t.Performer
+		case !strings.EqualFold(t.Performer, shared):
+			return "", true
+		}
+	}
+	return shared, false
+}
+
+// RemValue ищет среди комментариев диска REM вида "REM KEY значение" и возвращает
+// значение первого из них. Комментарии треков не учитываются.
+// Ключ сравнивается без учета регистра, а кавычки вокруг значения удаляются.
+func (c *Cuesheet) RemValue(key string) (string, bool) {
+	return remValue(c.Rem, key)
+}
+
+// AllRem возвращает все комментарии CUE sheet: сначала комментарии диска
+// (Cuesheet.Rem), затем комментарии треков в порядке следования треков.
+func (c *Cuesheet) AllRem() []string {
+	all := slices.Clone(c.Rem)
+	for _, f := range c.Files {
+		for _, t := range f.Tracks {
+			all = append(all, t.Rem...)
+		}
+	}
+	return all
+}
+
+// RemValue ищет REM с указанным ключом среди комментариев трека (см. Cuesheet.RemValue).
+func (t *Track) RemValue(key string) (string, bool) {
+	return remValue(t.Rem, key)
+}
+
+// inheritedRem возвращает значение REM трека, а если его нет — значение REM диска.
+func inheritedRem(t *Track, c *Cuesheet, key string) string {
+	if v, ok := t.RemValue(key); ok {
+		return v
+	}
+	v, _ := c.RemValue(key)
+	return v
+}
+
+// remValue ищет в rem первый комментарий с ключом key.
+func remValue(rem []string, key string) (string, bool) {
+	for _, line := range rem {
+		k, v, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if strings.EqualFold(k, key) {
+			v = strings.TrimSpace(v)
+			if len(v) >= 2 && v[0] == '"' && v[len(v)-1] == '"' {
+				v = v[1 : len(v)-1]
+			}
+			return v, true
+		}
+	}
+	return "", false
+}
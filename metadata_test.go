@@ -0,0 +1,199 @@
+package gocue
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTrack_Effective checks metadata inheritance and compilation detection.
+func TestTrack_Effective(t *testing.T) {
+	testCases := []struct {
+		name  string
+		cue   string
+		track int
+		want  Metadata
+	}{
+		{
+			name: "inherits album values",
+			cue: `
+REM GENRE Rock
+REM DATE 1994
+REM DISCNUMBER 1/2
+PERFORMER "Band"
+SONGWRITER "Writer"
+TITLE "Album"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    ISRC USABC9400001
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    SONGWRITER "Guest Writer"
+    REM GENRE Ballad
+    INDEX 01 03:00:00
+`,
+			track: 2,
+			want: Metadata{
+				Title: "Two", Artist: "Band", AlbumArtist: "Band", Album: "Album",
+				Composer: "Guest Writer", Genre: "Ballad", Date: "1994",
+				TrackNumber: 2, TotalTracks: 2, DiscNumber: 1, TotalDiscs: 2,
+			},
+		},
+		{
+			name: "compilation without album performer",
+			cue: `
+TITLE "Hits"
+REM TOTALDISCS 3
+REM DISCNUMBER 2
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    PERFORMER "Alice"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PERFORMER "Bob"
+    INDEX 01 03:00:00
+`,
+			track: 1,
+			want: Metadata{
+				Artist: "Alice", AlbumArtist: VariousArtists, Album: "Hits",
+				TrackNumber: 1, TotalTracks: 2, DiscNumber: 2, TotalDiscs: 3, Compilation: true,
+			},
+		},
+		{
+			name: "shared track performer becomes album artist",
+			cue: `
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    PERFORMER "Alice"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PERFORMER "alice"
+    INDEX 01 03:00:00
+`,
+			track: 2,
+			want:  Metadata{Artist: "alice", AlbumArtist: "Alice", TrackNumber: 2, TotalTracks: 2},
+		},
+		{
+			name: "track performer differs from album performer",
+			cue: `
+PERFORMER "Alice"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PERFORMER "Alice feat. Bob"
+    INDEX 01 03:00:00
+`,
+			track: 1,
+			want:  Metadata{Artist: "Alice", AlbumArtist: "Alice", TrackNumber: 1, TotalTracks: 2, Compilation: true},
+		},
+		{
+			name: "various artists album performer",
+			cue: `
+PERFORMER "VA"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+`,
+			track: 1,
+			want:  Metadata{Artist: "VA", AlbumArtist: "VA", TrackNumber: 1, TotalTracks: 1, Compilation: true},
+		},
+		{
+			name: "explicit compilation flag wins",
+			cue: `
+REM COMPILATION false
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    PERFORMER "Alice"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    PERFORMER "Bob"
+    INDEX 01 03:00:00
+`,
+			track: 2,
+			want:  Metadata{Artist: "Bob", TrackNumber: 2, TotalTracks: 2},
+		},
+		{
+			name: "CD-TEXT composer and genre commands",
+			cue: `
+REM GENRE Rock
+SONGWRITER "Lyricist"
+COMPOSER "Album Composer"
+GENRE "Jazz"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    SONGWRITER "Guest Lyricist"
+    COMPOSER "Guest Composer"
+    INDEX 01 03:00:00
+`,
+			track: 1,
+			want:  Metadata{Composer: "Album Composer", Genre: "Jazz", TrackNumber: 1, TotalTracks: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet, err := Parse(strings.NewReader(tc.cue))
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			got := sheet.Track(tc.track).Effective()
+			if got != tc.want {
+				t.Errorf("Effective() got\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRemValue checks REM key lookup.
+func TestRemValue(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`
+REM genre "Progressive Rock"
+REM COMMENT ExactAudioCopy v1.0
+REM DATE
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    REM REPLAYGAIN_TRACK_GAIN -7.89 dB
+    INDEX 01 00:00:00
+`))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if v, ok := sheet.RemValue("GENRE"); !ok || v != "Progressive Rock" {
+		t.Errorf("RemValue(GENRE) got %q, %v", v, ok)
+	}
+	if v, ok := sheet.RemValue("comment"); !ok || v != "ExactAudioCopy v1.0" {
+		t.Errorf("RemValue(comment) got %q, %v", v, ok)
+	}
+	if v, ok := sheet.RemValue("DATE"); !ok || v != "" {
+		t.Errorf("RemValue(DATE) got %q, %v", v, ok)
+	}
+	if _, ok := sheet.RemValue("REPLAYGAIN_TRACK_GAIN"); ok {
+		t.Error("RemValue() found a track REM at the album level")
+	}
+	if v, ok := sheet.Track(1).RemValue("REPLAYGAIN_TRACK_GAIN"); !ok || v != "-7.89 dB" {
+		t.Errorf("Track.RemValue() got %q, %v", v, ok)
+	}
+	if len(sheet.Rem) != 3 || len(sheet.AllRem()) != 4 || sheet.AllRem()[3] != "REPLAYGAIN_TRACK_GAIN -7.89 dB" {
+		t.Errorf("got disc REMs %q, all REMs %q", sheet.Rem, sheet.AllRem())
+	}
+
+	// REM трека с тем же текстом не скрывает REM диска.
+	track := sheet.Track(1)
+	track.Rem = append(track.Rem, "DATE 1994")
+	if v, ok := sheet.RemValue("DATE"); !ok || v != "" {
+		t.Errorf("RemValue(DATE) after editing Track.Rem got %q, %v", v, ok)
+	}
+	sheet.Rem = append(sheet.Rem, "DATE 1994")
+	track.Rem = append(track.Rem, "DATE 1994")
+	var b strings.Builder
+	if err := Write(&b, sheet); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	if got := strings.Count(b.String(), "REM DATE 1994"); got != 3 {
+		t.Errorf("Write() wrote %d REM DATE 1994 lines, want 3:\n%s", got, b.String())
+	}
+}
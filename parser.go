@@ -1,267 +1,350 @@
 package gocue
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
 )
 
+// Parser разбирает CUE sheet с настраиваемым поведением.
+// Нулевое значение готово к использованию и работает так же, как функция Parse.
+// Один Parser можно использовать для любого числа файлов, в том числе
+// одновременно из нескольких горутин, если не вызывать при этом Handle;
+// буферы чтения переиспользуются между вызовами Parse.
+type Parser struct {
+	// Lenient включает нестрогий режим, в котором принимаются нестандартные
+	// конструкции, встречающиеся в реальных файлах: таймкоды INDEX с долями
+	// секунды ("MM:SS.ddd"), которые сохраняются в Index.Precise, незакрытые
+	// кавычки (значение идет до конца строки) и имя команды в кавычках.
+	Lenient bool
+
+	// MaxLineLength ограничивает длину строки (см. Decoder.MaxLineLength).
+	MaxLineLength int
+
+	// OnWarning получает предупреждения об исправленных строках
+	// (см. Decoder.OnWarning).
+	OnWarning func(Warning)
+
+	// handlers — обработчики команд, зарегистрированные через Handle.
+	handlers map[string]CommandFunc
+}
+
+// CommandFunc обрабатывает команду CUE. args — аргументы команды без ее имени,
+// с уже снятыми кавычками; срез действителен только во время вызова. Ошибка
+// прерывает разбор; парсер дополняет ее номером строки.
+type CommandFunc func(ctx *ParseContext, args []string) error
+
+// ParseContext — состояние разбора, доступное обработчику команды.
+// Обработчик может менять Sheet, File и Track, а присвоив File или Track
+// новые значения, переключить контекст последующих команд: смена File
+// действует как команда FILE, смена Track — как команда TRACK.
+type ParseContext struct {
+	Sheet   *Cuesheet
+	File    *File  // Текущий FILE; nil до первой команды FILE.
+	Track   *Track // Текущий TRACK; nil вне блока трека.
+	Line    int    // Номер строки, начиная с 1.
+	Command string // Имя команды в верхнем регистре.
+	Text    string // Строка целиком, без пробелов по краям.
+}
+
+// SetExt сохраняет значение value под ключом key в поле Ext ближайшего
+// элемента: трека, файла или всего CUE sheet.
+func (ctx *ParseContext) SetExt(key string, value any) {
+	ext := &ctx.Sheet.Ext
+	switch {
+	case ctx.Track != nil:
+		ext = &ctx.Track.Ext
+	case ctx.File != nil:
+		ext = &ctx.File.Ext
+	}
+	if *ext == nil {
+		*ext = make(map[string]any)
+	}
+	(*ext)[key] = value
+}
+
+// Handle регистрирует обработчик команды command (без учета регистра).
+// Обработчик вызывается вместо встроенной обработки, поэтому так можно
+// как добавить собственные команды, так и переопределить стандартные.
+// Команды, разобранные обработчиком, не попадают в Extensions; значения
+// удобно сохранять через ParseContext.SetExt. Повторная регистрация
+// заменяет обработчик, а nil его удаляет.
+func (p *Parser) Handle(command string, h CommandFunc) {
+	command = strings.ToUpper(command)
+	if h == nil {
+		delete(p.handlers, command)
+		return
+	}
+	if p.handlers == nil {
+		p.handlers = make(map[string]CommandFunc)
+	}
+	p.handlers[command] = h
+}
+
 // Parse читает и разбирает CUE sheet из предоставленного io.Reader.
 // В случае успеха возвращает указатель на полностью заполненную структуру Cuesheet.
 // В случае ошибки возвращает nil и ошибку, описывающую проблему.
 func Parse(r io.Reader) (*Cuesheet, error) {
-	sheet := &Cuesheet{}
-	scanner := bufio.NewScanner(r)
+	return (&Parser{}).Parse(r)
+}
 
-	var currentFile *File
-	var currentTrack *Track
-	var pendingIndices []Index // <-- ИЗМЕНЕНИЕ: Буфер для "опережающих" индексов
-	lineNum := 0
+// Parse читает и разбирает CUE sheet из r с учетом настроек парсера.
+// CUE sheet строится из событий Decoder; стиль перевода строки источника
+// сохраняется в Cuesheet.LineEnding.
+func (p *Parser) Parse(r io.Reader) (*Cuesheet, error) {
+	d, _ := decoderPool.Get().(*Decoder)
+	if d == nil {
+		d = &Decoder{}
+	}
+	d.Reset(r)
+	d.Lenient, d.MaxLineLength, d.custom = p.Lenient, p.MaxLineLength, p.handlers
+	d.OnWarning = p.OnWarning
+	defer releaseDecoder(d)
 
-	for scanner.Scan() {
-		lineNum++
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue // Пропускаем пустые строки
-		}
+	b := &builder{parser: p, sheet: &Cuesheet{}}
+	if err := d.Decode(b); err != nil {
+		return nil, err
+	}
 
-		parts, err := smartSplit(line)
-		if err != nil {
-			return nil, fmt.Errorf("line %d: invalid quoting: %w", lineNum, err)
-		}
+	b.sheet.LineEnding = d.LineEnding()
 
-		if len(parts) == 0 {
-			continue
-		}
+	// Финальный шаг: устанавливаем внутренние ссылки на родительские элементы.
+	// Это нужно для работы методов вроде track.Duration().
+	b.sheet.Relink()
 
-		command := strings.ToUpper(parts[0])
-		args := parts[1:]
+	return b.sheet, nil
+}
 
-		switch command {
-		case "REM":
-			if len(args) > 0 {
-				sheet.Rem = append(sheet.Rem, strings.Join(args, " "))
-			}
-		case "CATALOG":
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: CATALOG command requires an argument", lineNum)
-			}
-			sheet.Catalog = args[0]
-		case "CDTEXTFILE":
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: CDTEXTFILE command requires an argument", lineNum)
-			}
-			sheet.CDTextFile = args[0]
-		case "TITLE":
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: TITLE command requires an argument", lineNum)
-			}
-			title := args[0]
-			if currentTrack != nil {
-				currentTrack.Title = title
-			} else { // Глобальный контекст
-				sheet.Title = title
-			}
-		case "PERFORMER":
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: PERFORMER command requires an argument", lineNum)
-			}
-			performer := args[0]
-			if currentTrack != nil {
-				currentTrack.Performer = performer
-			} else { // Глобальный контекст
-				sheet.Performer = performer
-			}
-		case "SONGWRITER":
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: SONGWRITER command requires an argument", lineNum)
-			}
-			songwriter := args[0]
-			if currentTrack != nil {
-				currentTrack.Songwriter = songwriter
+// decoderPool хранит декодеры с их буферами между вызовами Parser.Parse.
+var decoderPool sync.Pool
+
+// releaseDecoder возвращает декодер в пул, не удерживая источник данных
+// и буферы, выросшие из-за слишком длинных строк.
+func releaseDecoder(d *Decoder) {
+	d.Reset(nil)
+	d.custom = nil
+	clear(d.args)
+	d.OnWarning = nil
+	if cap(d.buf) > DefaultMaxLineLength {
+		d.buf, d.clean = nil, nil
+	}
+	decoderPool.Put(d)
+}
+
+// builder собирает Cuesheet из событий декодера.
+type builder struct {
+	parser *Parser
+	sheet  *Cuesheet
+
+	currentFile    *File
+	currentTrack   *Track
+	pendingIndices []Index // Буфер для "опережающих" индексов
+	spanningTrack  *Track  // Трек из предыдущего FILE, у которого еще нет INDEX 01
+}
+
+// HandleEvent реализует Handler.
+func (b *builder) HandleEvent(ev *Event) error {
+	if err := b.apply(ev); err != nil {
+		return fmt.Errorf("line %d: %w", ev.Line, err)
+	}
+	return nil
+}
+
+// apply применяет событие к строящемуся CUE sheet.
+func (b *builder) apply(ev *Event) error {
+	switch ev.Kind {
+	case EventRem:
+		if ev.Value != "" {
+			if b.currentTrack != nil {
+				b.currentTrack.Rem = append(b.currentTrack.Rem, ev.Value)
 			} else { // Глобальный контекст
-				sheet.Songwriter = songwriter
-			}
-		case "FILE":
-			if len(args) < 2 {
-				return nil, fmt.Errorf("line %d: FILE command requires name and type arguments", lineNum)
-			}
-			file := &File{Name: args[0], Type: strings.ToUpper(args[1])}
-			sheet.Files = append(sheet.Files, file)
-			currentFile = file
-			currentTrack = nil   // Сбрасываем контекст трека при объявлении нового файла
-			pendingIndices = nil // ИЗМЕНЕНИЕ: Очищаем буфер индексов
-		case "TRACK":
-			if currentFile == nil {
-				return nil, fmt.Errorf("line %d: TRACK command found outside of a FILE context", lineNum)
-			}
-			if len(args) < 2 {
-				return nil, fmt.Errorf("line %d: TRACK command requires number and type arguments", lineNum)
-			}
-			num, err := strconv.Atoi(args[0])
-			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid track number: %w", lineNum, err)
+				b.sheet.Rem = append(b.sheet.Rem, ev.Value)
 			}
-			track := &Track{Number: num, Type: strings.ToUpper(args[1])}
-			// ИЗМЕНЕНИЕ: Присоединяем накопленные индексы к новому треку
-			if len(pendingIndices) > 0 {
-				track.Indices = append(track.Indices, pendingIndices...)
-				pendingIndices = nil // Очищаем буфер
-			}
-			currentFile.Tracks = append(currentFile.Tracks, track)
-			currentTrack = track
-		case "INDEX":
-			if len(args) < 2 {
-				return nil, fmt.Errorf("line %d: INDEX command requires number and timecode arguments", lineNum)
-			}
-			num, err := strconv.Atoi(args[0])
-			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid index number: %w", lineNum, err)
-			}
-			timecode, err := parseTimecode(args[1])
-			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid timecode for INDEX: %w", lineNum, err)
+		}
+	case EventMeta:
+		return b.meta(ev)
+	case EventFileStart:
+		file := &File{Name: ev.Name, Type: ev.Type}
+		b.sheet.Files = append(b.sheet.Files, file)
+		// В раскладке "паузы в конце предыдущего файла" INDEX 00 трека записан
+		// в старом FILE, а его INDEX 01 придет уже после нового FILE.
+		b.spanningTrack = nil
+		if b.currentTrack != nil {
+			if _, ok := b.currentTrack.Index(1); !ok {
+				b.spanningTrack = b.currentTrack
 			}
-			index := Index{Number: num, Time: timecode}
+		}
+		b.currentFile = file
+		b.currentTrack = nil   // Сбрасываем контекст трека при объявлении нового файла
+		b.pendingIndices = nil // Очищаем буфер индексов
+	case EventTrackStart:
+		if b.currentFile == nil {
+			return errors.New("TRACK command found outside of a FILE context")
+		}
+		track := &Track{Number: ev.Number, Type: ev.Type}
+		// Присоединяем накопленные индексы к новому треку
+		if len(b.pendingIndices) > 0 {
+			track.Indices = append(track.Indices, b.pendingIndices...)
+			b.pendingIndices = nil
+		}
+		b.currentFile.Tracks = append(b.currentFile.Tracks, track)
+		b.currentTrack = track
+		b.spanningTrack = nil
+	case EventIndex:
+		index := ev.Index
+		if b.currentTrack != nil {
+			// Если контекст трека уже есть, добавляем как обычно
+			b.currentTrack.Indices = append(b.currentTrack.Indices, index)
+		} else if b.spanningTrack != nil && index.Number >= 1 {
+			// Продолжение трека из предыдущего FILE: трек переезжает в файл,
+			// где лежит его INDEX 01, а ранние индексы остаются в старом файле.
+			moveTrackToFile(b.sheet, b.spanningTrack, b.currentFile)
+			b.spanningTrack.Indices = append(b.spanningTrack.Indices, index)
+			b.currentTrack = b.spanningTrack
+			b.spanningTrack = nil
+		} else if b.currentFile != nil {
+			// Если есть контекст файла, но нет трека, добавляем в буфер
+			b.pendingIndices = append(b.pendingIndices, index)
+		} else {
+			// Если нет даже контекста файла, это ошибка
+			return errors.New("INDEX command found outside of a FILE context")
+		}
+	case EventUnknown:
+		if h, ok := b.parser.handlers[ev.Command]; ok {
+			return b.handle(h, ev)
+		}
+		// Неизвестные команды сохраняются в ближайшем контексте, чтобы
+		// вернуть их на место при записи.
+		ext := Command{Name: ev.Command, Args: slices.Clone(ev.Args)}
+		switch {
+		case b.currentTrack != nil:
+			b.currentTrack.Extensions = append(b.currentTrack.Extensions, ext)
+		case b.currentFile != nil:
+			b.currentFile.Extensions = append(b.currentFile.Extensions, ext)
+		default:
+			b.sheet.Extensions = append(b.sheet.Extensions, ext)
+		}
+	}
+	return nil
+}
 
-			// ИЗМЕНЕНИЕ: Главная логика исправления
-			if currentTrack != nil {
-				// Если контекст трека уже есть, добавляем как обычно
-				currentTrack.Indices = append(currentTrack.Indices, index)
-			} else if currentFile != nil {
-				// Если есть контекст файла, но нет трека, добавляем в буфер
-				pendingIndices = append(pendingIndices, index)
-			} else {
-				// Если нет даже контекста файла, это ошибка
-				return nil, fmt.Errorf("line %d: INDEX command found outside of a FILE context", lineNum)
-			}
+// meta применяет команду метаданных к диску или текущему треку.
+func (b *builder) meta(ev *Event) error {
+	track := b.currentTrack
+	switch ev.Command {
+	case "CATALOG":
+		b.sheet.Catalog = ev.Value
+	case "CDTEXTFILE":
+		b.sheet.CDTextFile = ev.Value
+	case "TITLE", "PERFORMER", "SONGWRITER", "COMPOSER", "ARRANGER", "MESSAGE":
+		if track != nil {
+			*trackTextField(track, ev.Command) = ev.Value
+		} else { // Глобальный контекст
+			*sheetTextField(b.sheet, ev.Command) = ev.Value
+		}
+	case "GENRE", "DISC_ID", "UPC_EAN", "TOC_INFO", "TOC_INFO2", "SIZE_INFO":
+		// Эти поля CD-TEXT относятся только ко всему диску.
+		*sheetTextField(b.sheet, ev.Command) = ev.Value
+	default: // PREGAP, POSTGAP, FLAGS и ISRC допустимы только внутри трека.
+		if track == nil {
+			return fmt.Errorf("%s command found outside of a TRACK context", ev.Command)
+		}
+		switch ev.Command {
 		case "PREGAP":
-			if currentTrack == nil {
-				return nil, fmt.Errorf("line %d: PREGAP command found outside of a TRACK context", lineNum)
-			}
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: PREGAP command requires a timecode argument", lineNum)
-			}
-			timecode, err := parseTimecode(args[0])
-			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid timecode for PREGAP: %w", lineNum, err)
-			}
-			currentTrack.Pregap = timecode
+			track.Pregap = ev.Time
 		case "POSTGAP":
-			if currentTrack == nil {
-				return nil, fmt.Errorf("line %d: POSTGAP command found outside of a TRACK context", lineNum)
-			}
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: POSTGAP command requires a timecode argument", lineNum)
-			}
-			timecode, err := parseTimecode(args[0])
-			if err != nil {
-				return nil, fmt.Errorf("line %d: invalid timecode for POSTGAP: %w", lineNum, err)
-			}
-			currentTrack.Postgap = timecode
+			track.Postgap = ev.Time
 		case "FLAGS":
-			if currentTrack == nil {
-				return nil, fmt.Errorf("line %d: FLAGS command found outside of a TRACK context", lineNum)
-			}
-			currentTrack.Flags = append(currentTrack.Flags, args...)
+			track.Flags = append(track.Flags, ev.Args...)
 		case "ISRC":
-			if currentTrack == nil {
-				return nil, fmt.Errorf("line %d: ISRC command found outside of a TRACK context", lineNum)
-			}
-			if len(args) < 1 {
-				return nil, fmt.Errorf("line %d: ISRC command requires an argument", lineNum)
-			}
-			currentTrack.ISRC = args[0]
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %w", err)
-	}
-
-	// Финальный шаг: проходим по созданным структурам и устанавливаем
-	// внутренние ссылки на родительские элементы. Это нужно для работы
-	// методов вроде track.Duration().
-	for _, f := range sheet.Files {
-		f.parentSheet = sheet
-		for _, t := range f.Tracks {
-			t.parentFile = f
+			track.ISRC = ev.Value
 		}
 	}
-
-	return sheet, nil
+	return nil
 }
 
-// parseTimecode разбирает строку формата "MM:SS:FF" в структуру Timecode.
-func parseTimecode(s string) (Timecode, error) {
-	parts := strings.Split(s, ":")
-	if len(parts) != 3 {
-		return Timecode{}, errors.New("timecode must be in MM:SS:FF format")
-	}
-
-	minutes, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return Timecode{}, fmt.Errorf("invalid minutes value: %s", parts[0])
+// handle вызывает обработчик команды и применяет смену контекста.
+func (b *builder) handle(h CommandFunc, ev *Event) error {
+	ctx := &ParseContext{Sheet: b.sheet, File: b.currentFile, Track: b.currentTrack, Line: ev.Line, Command: ev.Command, Text: ev.Text}
+	if err := h(ctx, ev.Args); err != nil {
+		return fmt.Errorf("%s: %w", ev.Command, err)
 	}
-
-	seconds, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return Timecode{}, fmt.Errorf("invalid seconds value: %s", parts[1])
+	if ctx.File != b.currentFile {
+		b.currentFile, b.currentTrack = ctx.File, nil
+		b.pendingIndices, b.spanningTrack = nil, nil
 	}
-	if seconds > 59 {
-		return Timecode{}, fmt.Errorf("seconds value cannot exceed 59: %d", seconds)
+	if ctx.Track != b.currentTrack {
+		b.currentTrack, b.spanningTrack = ctx.Track, nil
 	}
+	return nil
+}
 
-	frames, err := strconv.Atoi(parts[2])
-	if err != nil {
-		return Timecode{}, fmt.Errorf("invalid frames value: %s", parts[2])
+// sheetTextField возвращает поле диска, которое задает текстовая команда command.
+func sheetTextField(sheet *Cuesheet, command string) *string {
+	switch command {
+	case "TITLE":
+		return &sheet.Title
+	case "PERFORMER":
+		return &sheet.Performer
+	case "SONGWRITER":
+		return &sheet.Songwriter
+	case "COMPOSER":
+		return &sheet.Composer
+	case "ARRANGER":
+		return &sheet.Arranger
+	case "MESSAGE":
+		return &sheet.Message
+	case "GENRE":
+		return &sheet.Genre
+	case "DISC_ID":
+		return &sheet.DiscID
+	case "UPC_EAN":
+		return &sheet.UPCEAN
+	case "TOC_INFO":
+		return &sheet.TOCInfo
+	case "TOC_INFO2":
+		return &sheet.TOCInfo2
+	case "SIZE_INFO":
+		return &sheet.SizeInfo
 	}
-	if frames >= FramesPerSecond {
-		return Timecode{}, fmt.Errorf("frames value must be less than %d: %d", FramesPerSecond, frames)
-	}
-
-	return Timecode{Minutes: minutes, Seconds: seconds, Frames: frames}, nil
+	panic("gocue: unknown text command " + command)
 }
 
-// smartSplit разделяет строку на части, учитывая двойные кавычки.
-// Аргументы в кавычках считаются единым целым.
-func smartSplit(line string) ([]string, error) {
-	var result []string
-	var current strings.Builder
-	inQuote := false
+// trackTextField возвращает поле трека, которое задает текстовая команда command.
+func trackTextField(track *Track, command string) *string {
+	switch command {
+	case "TITLE":
+		return &track.Title
+	case "PERFORMER":
+		return &track.Performer
+	case "SONGWRITER":
+		return &track.Songwriter
+	case "COMPOSER":
+		return &track.Composer
+	case "ARRANGER":
+		return &track.Arranger
+	case "MESSAGE":
+		return &track.Message
+	}
+	panic("gocue: unknown text command " + command)
+}
 
-	for i, r := range line {
-		switch r {
-		case '"':
-			inQuote = !inQuote
-		case ' ', '\t':
-			if inQuote {
-				current.WriteRune(r)
-			} else {
-				if current.Len() > 0 {
-					result = append(result, current.String())
-					current.Reset()
+// moveTrackToFile переносит последний трек предыдущего файла в файл to.
+// Уже добавленные индексы трека помечаются как принадлежащие старому файлу.
+func moveTrackToFile(sheet *Cuesheet, track *Track, to *File) {
+	for _, f := range sheet.Files {
+		if n := len(f.Tracks); n > 0 && f.Tracks[n-1] == track {
+			for i := range track.Indices {
+				if track.Indices[i].File == nil {
+					track.Indices[i].File = f
 				}
 			}
-		default:
-			// Команда не может быть в кавычках
-			if len(result) == 0 && current.Len() == 0 && r == '"' {
-				return nil, errors.New("command cannot be quoted")
-			}
-			current.WriteRune(r)
-		}
-		// Проверка на незакрытую кавычку в конце строки
-		if i == len(line)-1 && inQuote {
-			return nil, errors.New("mismatched quotes")
+			f.Tracks = f.Tracks[:n-1]
+			break
 		}
 	}
-
-	if current.Len() > 0 {
-		result = append(result, current.String())
-	}
-
-	return result, nil
+	to.Tracks = append(to.Tracks, track)
 }
@@ -1,8 +1,12 @@
 package gocue
 
 import (
+	"errors"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestParse_Full is a comprehensive test for a valid, feature-rich CUE sheet.
@@ -191,3 +195,170 @@ func TestParse_ErrorCases(t *testing.T) {
 		})
 	}
 }
+
+// TestParse_IndexInPreviousFile checks the "gaps appended" layout where a track's
+// INDEX 00 is written under the previous FILE and its INDEX 01 under its own.
+func TestParse_IndexInPreviousFile(t *testing.T) {
+	cueSheetContent := `
+FILE "01.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 04:10:20
+FILE "02.wav" WAVE
+    INDEX 01 00:00:00
+  TRACK 03 AUDIO
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+`
+	sheet, err := Parse(strings.NewReader(cueSheetContent))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	file1, file2 := sheet.Files[0], sheet.Files[1]
+	if len(file1.Tracks) != 1 || len(file2.Tracks) != 2 {
+		t.Fatalf("got %d and %d tracks per file, want 1 and 2", len(file1.Tracks), len(file2.Tracks))
+	}
+
+	track2 := file2.Tracks[0]
+	if track2.Number != 2 || len(track2.Indices) != 2 {
+		t.Fatalf("track 2 was not moved to its INDEX 01 file: %+v", track2)
+	}
+	if track2.IndexFile(track2.Indices[0]) != file1 || track2.IndexFile(track2.Indices[1]) != file2 {
+		t.Error("track 2 indices point at the wrong files")
+	}
+	if len(file2.Tracks[1].Indices) != 2 {
+		t.Errorf("track 3 got %d indices, want 2", len(file2.Tracks[1].Indices))
+	}
+
+	// Трек 1 идет до конца своего файла, включая паузу трека 2.
+	if file1.Tracks[0].Duration() != 0 {
+		t.Errorf("track 1 duration got %v, want 0 (read to EOF)", file1.Tracks[0].Duration())
+	}
+	if got := track2.Duration().Seconds(); got != 182 {
+		t.Errorf("track 2 duration got %vs, want 182s", got)
+	}
+}
+
+// TestParse_ExtendedCommands checks the CD-TEXT commands of ImgBurn-style cues
+// and that unknown commands are kept in their context.
+func TestParse_ExtendedCommands(t *testing.T) {
+	cueSheetContent := `
+CATALOG 0074643135527
+COMPOSER "Bill Evans"
+ARRANGER "Gil Evans"
+MESSAGE "Remastered"
+GENRE "Modal Jazz"
+DISC_ID 3A0A5E05
+UPC_EAN 0074643135527
+TOC_INFO 0000000000
+SIZE_INFO 0 1 5
+X_FIRST one
+TITLE "Kind of Blue"
+X_SECOND "two words" three
+FILE "a.wav" WAVE
+  X_FILE
+  TRACK 01 AUDIO
+    COMPOSER "Miles Davis"
+    MESSAGE "Take 3"
+    X_TRACK yes
+    INDEX 01 00:00:00
+`
+	sheet, err := Parse(strings.NewReader(cueSheetContent))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if sheet.Composer != "Bill Evans" || sheet.Arranger != "Gil Evans" || sheet.Message != "Remastered" || sheet.Genre != "Modal Jazz" {
+		t.Errorf("got disc text %q / %q / %q / %q", sheet.Composer, sheet.Arranger, sheet.Message, sheet.Genre)
+	}
+	if sheet.DiscID != "3A0A5E05" || sheet.UPCEAN != "0074643135527" || sheet.TOCInfo != "0000000000" || sheet.SizeInfo != "0 1 5" {
+		t.Errorf("got disc codes %q / %q / %q / %q", sheet.DiscID, sheet.UPCEAN, sheet.TOCInfo, sheet.SizeInfo)
+	}
+	track := sheet.Files[0].Tracks[0]
+	if track.Composer != "Miles Davis" || track.Message != "Take 3" || track.Arranger != "" {
+		t.Errorf("got track text %q / %q / %q", track.Composer, track.Message, track.Arranger)
+	}
+
+	wantSheet := []Command{{Name: "X_FIRST", Args: []string{"one"}}, {Name: "X_SECOND", Args: []string{"two words", "three"}}}
+	if !reflect.DeepEqual(sheet.Extensions, wantSheet) {
+		t.Errorf("sheet extensions got %+v, want %+v", sheet.Extensions, wantSheet)
+	}
+	if want := []Command{{Name: "X_FILE", Args: []string{}}}; !reflect.DeepEqual(sheet.Files[0].Extensions, want) {
+		t.Errorf("file extensions got %+v, want %+v", sheet.Files[0].Extensions, want)
+	}
+	if want := []Command{{Name: "X_TRACK", Args: []string{"yes"}}}; !reflect.DeepEqual(track.Extensions, want) {
+		t.Errorf("track extensions got %+v, want %+v", track.Extensions, want)
+	}
+}
+
+// TestParser_Handle checks custom command handlers: vendor commands, overriding
+// a built-in command, switching context and error reporting.
+func TestParser_Handle(t *testing.T) {
+	p := &Parser{}
+	p.Handle("x_rating", func(ctx *ParseContext, args []string) error {
+		if len(args) != 1 {
+			return errors.New("want one argument")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return err
+		}
+		ctx.SetExt("rating", n)
+		return nil
+	})
+	// Вариант TITLE, который пишет название в верхнем регистре.
+	p.Handle("TITLE", func(ctx *ParseContext, args []string) error {
+		if ctx.Track != nil {
+			ctx.Track.Title = strings.ToUpper(args[0])
+		} else {
+			ctx.Sheet.Title = strings.ToUpper(args[0])
+		}
+		return nil
+	})
+	// SONG открывает трек без номера: номер берется по порядку.
+	p.Handle("SONG", func(ctx *ParseContext, args []string) error {
+		track, err := ctx.File.AddTrack(len(ctx.File.Tracks)+1, "AUDIO")
+		ctx.Track = track
+		return err
+	})
+
+	sheet, err := p.Parse(strings.NewReader(`
+TITLE "album"
+X_RATING 5
+FILE "a.wav" WAVE
+  X_RATING 4
+  SONG
+    TITLE "one"
+    X_RATING 3
+    INDEX 01 00:00:00
+  SONG
+    INDEX 01 03:00:00
+`))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	if sheet.Title != "ALBUM" || sheet.Ext["rating"] != 5 || sheet.Files[0].Ext["rating"] != 4 {
+		t.Errorf("got sheet %q, ext %v, file ext %v", sheet.Title, sheet.Ext, sheet.Files[0].Ext)
+	}
+	tracks := sheet.Files[0].Tracks
+	if len(tracks) != 2 || tracks[0].Title != "ONE" || tracks[0].Ext["rating"] != 3 || tracks[1].Number != 2 {
+		t.Fatalf("got tracks %+v", tracks)
+	}
+	if tracks[1].StartTime() != (Timecode{Minutes: 3}) || tracks[0].Duration() != 3*time.Minute {
+		t.Errorf("got track 2 start %v, track 1 duration %v", tracks[1].StartTime(), tracks[0].Duration())
+	}
+	if len(sheet.Extensions) != 0 {
+		t.Errorf("handled commands leaked into Extensions: %+v", sheet.Extensions)
+	}
+
+	_, err = p.Parse(strings.NewReader("TITLE \"a\"\nX_RATING high\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: X_RATING: ") {
+		t.Errorf("got error %v, want it to name line 2 and the command", err)
+	}
+
+	p.Handle("TITLE", nil)
+	sheet, err = p.Parse(strings.NewReader(`TITLE "album"`))
+	if err != nil || sheet.Title != "album" {
+		t.Errorf("built-in TITLE not restored: %v, %v", sheet, err)
+	}
+}
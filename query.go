@@ -0,0 +1,97 @@
+package gocue
+
+import (
+	"iter"
+	"strings"
+)
+
+// Tracks возвращает итератор по всем трекам CUE sheet в порядке их следования
+// во всех файлах. Первое значение — порядковый номер трека в CUE sheet (с нуля).
+//
+//	for i, track := range sheet.Tracks() {
+//		fmt.Println(i, track.Title)
+//	}
+func (c *Cuesheet) Tracks() iter.Seq2[int, *Track] {
+	return func(yield func(int, *Track) bool) {
+		i := 0
+		for _, f := range c.Files {
+			for _, t := range f.Tracks {
+				if !yield(i, t) {
+					return
+				}
+				i++
+			}
+		}
+	}
+}
+
+// Track возвращает трек с указанным номером или nil, если такого трека нет.
+func (c *Cuesheet) Track(number int) *Track {
+	for _, t := range c.Tracks() {
+		if t.Number == number {
+			return t
+		}
+	}
+	return nil
+}
+
+// TrackByISRC возвращает трек с указанным кодом ISRC или nil, если такого трека нет.
+// Регистр и дефисы при сравнении не учитываются: "US-S1Z-99-00001" и
+// "USS1Z9900001" считаются одним кодом.
+func (c *Cuesheet) TrackByISRC(isrc string) *Track {
+	want := normalizeISRC(isrc)
+	if want == "" {
+		return nil
+	}
+	for _, t := range c.Tracks() {
+		if normalizeISRC(t.ISRC) == want {
+			return t
+		}
+	}
+	return nil
+}
+
+// TracksWhere возвращает итератор по трекам, для которых match возвращает true.
+func (c *Cuesheet) TracksWhere(match func(*Track) bool) iter.Seq[*Track] {
+	return func(yield func(*Track) bool) {
+		for _, t := range c.Tracks() {
+			if match(t) && !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// AudioTracks возвращает итератор по аудиотрекам (TRACK nn AUDIO).
+func (c *Cuesheet) AudioTracks() iter.Seq[*Track] {
+	return c.TracksWhere((*Track).IsAudio)
+}
+
+// DataTracks возвращает итератор по трекам с данными (MODE1/2352, MODE2/2352, CDG и т.д.).
+func (c *Cuesheet) DataTracks() iter.Seq[*Track] {
+	return c.TracksWhere(func(t *Track) bool { return !t.IsAudio() })
+}
+
+// IsAudio сообщает, является ли трек аудиотреком.
+func (t *Track) IsAudio() bool {
+	return strings.EqualFold(t.Type, "AUDIO")
+}
+
+// Index возвращает порядковый номер файла в Cuesheet.Files (с нуля)
+// или -1, если файл не привязан к CUE sheet.
+func (f *File) Index() int {
+	if f.parentSheet == nil {
+		return -1
+	}
+	for i, file := range f.parentSheet.Files {
+		if file == f {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeISRC приводит код ISRC к виду без дефисов и пробелов в верхнем регистре.
+func normalizeISRC(isrc string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isrc))
+}
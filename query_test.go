@@ -0,0 +1,65 @@
+package gocue
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCuesheet_Queries checks track iteration and lookup helpers.
+func TestCuesheet_Queries(t *testing.T) {
+	cue := `
+FILE "game.bin" BINARY
+  TRACK 01 MODE1/2352
+    INDEX 01 00:00:00
+FILE "audio.wav" WAVE
+  TRACK 02 AUDIO
+    ISRC US-S1Z-99-00001
+    INDEX 01 00:00:00
+  TRACK 03 AUDIO
+    INDEX 01 03:00:00
+`
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+
+	var numbers []int
+	for i, track := range sheet.Tracks() {
+		if i != len(numbers) {
+			t.Errorf("Tracks() yielded position %d, want %d", i, len(numbers))
+		}
+		numbers = append(numbers, track.Number)
+	}
+	if !slices.Equal(numbers, []int{1, 2, 3}) {
+		t.Errorf("Tracks() got %v, want [1 2 3]", numbers)
+	}
+
+	if tr := sheet.Track(3); tr == nil || tr.StartTime().Minutes != 3 {
+		t.Errorf("Track(3) got %+v", tr)
+	}
+	if sheet.Track(4) != nil {
+		t.Error("Track(4) found a missing track")
+	}
+	if tr := sheet.TrackByISRC("uss1z9900001"); tr == nil || tr.Number != 2 {
+		t.Errorf("TrackByISRC() got %+v, want track 2", tr)
+	}
+	if sheet.TrackByISRC("") != nil {
+		t.Error("TrackByISRC(\"\") matched a track without ISRC")
+	}
+
+	audio := slices.Collect(sheet.AudioTracks())
+	data := slices.Collect(sheet.DataTracks())
+	if len(audio) != 2 || len(data) != 1 || data[0].Number != 1 {
+		t.Errorf("got %d audio and %d data tracks, want 2 and 1", len(audio), len(data))
+	}
+
+	// Досрочный выход из цикла не должен приводить к панике.
+	for range sheet.AudioTracks() {
+		break
+	}
+
+	if sheet.Files[1].Index() != 1 || (&File{}).Index() != -1 {
+		t.Error("File.Index() returned a wrong position")
+	}
+}
@@ -0,0 +1,198 @@
+package gocue
+
+import (
+	"errors"
+	"strings"
+)
+
+// Правила кавычек в аргументах команд CUE.
+//
+// Аргументы разделяются пробелами и табуляциями. Участок в двойных кавычках
+// может содержать пробелы; сами кавычки в значение не входят. Внутри участка:
+//
+//   - кавычка закрывает участок, если за ней идет пробел или конец строки
+//     и до конца строки остается четное число кавычек. Иначе это кавычка
+//     внутри значения: TITLE "12" Single" дает 12" Single;
+//   - \" — экранированная кавычка, "" — удвоенная кавычка; обе дают ". Обратный
+//     слеш перед закрывающей кавычкой остается в значении ("C:\dir\"), как
+//     и перед удвоенной кавычкой, если за ней нет закрывающей (a\""b);
+//     остальные обратные слеши не меняются;
+//   - если участок не закрыт до конца строки, закрывающей считается последняя
+//     кавычка внутри значения, а текст после нее продолжает аргумент:
+//     "Track 01".wav дает Track 01.wav.
+//
+// Участок в кавычках может начинаться и внутри аргумента: a"b c"d дает ab cd.
+// Если кавычка так и осталась незакрытой, в строгом режиме это ошибка, а в
+// нестрогом участок продолжается до конца строки. Имя команды не может быть
+// в кавычках.
+//
+// quote записывает значения по этим же правилам, удваивая кавычки, так что
+// smartSplit возвращает исходное значение для любой строки.
+
+// errUnclosedQuote возвращается для кавычки, которую нечем закрыть.
+var errUnclosedQuote = errors.New("mismatched quotes")
+
+// smartSplit разделяет строку на аргументы по описанным выше правилам
+// и добавляет их в dst. Простые аргументы — подстроки line, поэтому обычная
+// строка разбирается без выделения памяти; новая строка собирается, только
+// если значение нужно изменить. В нестрогом режиме (lenient) незакрытая
+// кавычка не считается ошибкой.
+func smartSplit(line string, dst []string, lenient bool) ([]string, error) {
+	s := splitter{line: line, quotes: strings.Count(line, `"`), lenient: lenient}
+	for {
+		for s.i < len(line) && isSpace(line[s.i]) {
+			s.i++
+		}
+		if s.i == len(line) {
+			return dst, nil
+		}
+		token, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, token)
+	}
+}
+
+// isSpace сообщает, что байт разделяет аргументы.
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t'
+}
+
+// splitter — состояние разбора строки на аргументы.
+type splitter struct {
+	line    string
+	i       int // Текущая позиция.
+	quotes  int // Число кавычек в line[i:].
+	lenient bool
+}
+
+// closes сообщает, закрывает ли кавычка в позиции q участок в кавычках.
+// rest — число кавычек после q.
+func (s *splitter) closes(q, rest int) bool {
+	return (q+1 == len(s.line) || isSpace(s.line[q+1])) && rest%2 == 0
+}
+
+// token читает аргумент, начинающийся в текущей позиции.
+func (s *splitter) token() (string, error) {
+	line, start := s.line, s.i
+	for s.i < len(line) && !isSpace(line[s.i]) && line[s.i] != '"' {
+		s.i++
+	}
+	if s.i == len(line) || isSpace(line[s.i]) {
+		return line[start:s.i], nil // Аргумент без кавычек.
+	}
+	if s.i == start {
+		// Быстрый путь: "значение" без кавычек и обратного слеша перед закрывающей.
+		if end := strings.IndexByte(line[s.i+1:], '"') + s.i + 1; end > s.i && line[end-1] != '\\' && s.closes(end, s.quotes-2) {
+			s.i, s.quotes = end+1, s.quotes-2
+			return line[start+1 : end], nil
+		}
+	}
+
+	b := []byte(line[start:s.i])
+	for s.i < len(line) && !isSpace(line[s.i]) {
+		if line[s.i] != '"' {
+			b = append(b, line[s.i])
+			s.i++
+			continue
+		}
+		var err error
+		if b, err = s.quoted(b); err != nil {
+			return "", err
+		}
+	}
+	return string(b), nil
+}
+
+// quoted дописывает в b участок в кавычках, начинающийся в текущей позиции,
+// и оставляет позицию сразу после закрывающей кавычки.
+func (s *splitter) quoted(b []byte) ([]byte, error) {
+	line := s.line
+	s.i++ // Открывающая кавычка.
+	s.quotes--
+	last, lastLen, lastQuotes := -1, 0, 0 // Последняя кавычка внутри значения.
+
+	for s.i < len(line) {
+		c := line[s.i]
+		escaped := c == '\\' && s.i+1 < len(line) && line[s.i+1] == '"'
+		if c != '"' && !escaped {
+			b = append(b, c)
+			s.i++
+			continue
+		}
+		q := s.i
+		if escaped {
+			q++
+		}
+		doubled := q+1 < len(line) && line[q+1] == '"'
+
+		switch {
+		case s.closes(q, s.quotes-1):
+			if escaped {
+				b = append(b, '\\')
+			}
+			s.i, s.quotes = q+1, s.quotes-1
+			return b, nil
+		case escaped && doubled && !s.closes(q+1, s.quotes-2):
+			// a\""b: обратный слеш перед удвоенной кавычкой.
+			b = append(b, '\\')
+			s.i = q
+		case escaped:
+			b = append(b, '"')
+			s.i, s.quotes = q+1, s.quotes-1
+		case doubled:
+			b = append(b, '"')
+			s.i, s.quotes = q+2, s.quotes-2
+		default:
+			last, lastLen, lastQuotes = q, len(b), s.quotes-1
+			b = append(b, '"')
+			s.i, s.quotes = q+1, s.quotes-1
+		}
+	}
+
+	switch {
+	case last >= 0:
+		// Текст после закрывающей кавычки продолжает аргумент.
+		s.i, s.quotes = last+1, lastQuotes
+		return b[:lastLen], nil
+	case s.lenient:
+		return b, nil // Участок идет до конца строки.
+	}
+	return nil, errUnclosedQuote
+}
+
+// quote заключает значение в двойные кавычки. Кавычки внутри значения
+// записываются как есть, если строка читается обратно однозначно: так пишут
+// EAC и foobar2000, которые не понимают удвоенных кавычек. Иначе они
+// удваиваются (см. quoteDoubled). Проверка однозначности верна, если после
+// значения в строке нет других кавычек.
+func quote(s string) string {
+	raw := `"` + s + `"`
+	if strings.Contains(s, `"`) {
+		if args, err := smartSplit(raw, nil, false); err != nil || len(args) != 1 || args[0] != s {
+			return quoteDoubled(s)
+		}
+	}
+	return raw
+}
+
+// quoteDoubled заключает значение в двойные кавычки, удваивая кавычки внутри него.
+// Такое значение читается однозначно независимо от остальной строки.
+func quoteDoubled(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// needsQuotes сообщает, что значение пустое или содержит пробелы или кавычки.
+func needsQuotes(s string) bool {
+	return s == "" || strings.ContainsAny(s, " \t\"")
+}
+
+// quoteIfNeeded заключает в кавычки значение, для которого это необходимо
+// (см. needsQuotes); остальные значения возвращаются как есть.
+func quoteIfNeeded(s string) string {
+	if needsQuotes(s) {
+		return quote(s)
+	}
+	return s
+}
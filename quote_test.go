@@ -0,0 +1,109 @@
+package gocue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSmartSplit is a matrix of quoting cases seen in real cue files.
+func TestSmartSplit(t *testing.T) {
+	testCases := []struct {
+		name    string
+		line    string
+		lenient bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "plain", line: "INDEX\t01   00:00:00", want: []string{"INDEX", "01", "00:00:00"}},
+		{name: "quoted", line: `TITLE "Kind of Blue"`, want: []string{"TITLE", "Kind of Blue"}},
+		{name: "empty", line: `TITLE ""`, want: []string{"TITLE", ""}},
+		{name: "two empty", line: `X "" ""`, want: []string{"X", "", ""}},
+		{name: "multi-byte UTF-8", line: `TITLE "Зимняя дорога"`, want: []string{"TITLE", "Зимняя дорога"}},
+		{name: "unescaped inner quote", line: `TITLE "12" Single"`, want: []string{"TITLE", `12" Single`}},
+		{name: "unescaped quoted word", line: `TITLE "He said "hi" there"`, want: []string{"TITLE", `He said "hi" there`}},
+		{name: "backslash escapes", line: `TITLE "He said \"hi\""`, want: []string{"TITLE", `He said "hi"`}},
+		{name: "escape before space", line: `TITLE "say \"hi\" now"`, want: []string{"TITLE", `say "hi" now`}},
+		{name: "doubled quotes", line: `TITLE "He said ""hi"""`, want: []string{"TITLE", `He said "hi"`}},
+		{name: "trailing backslash", line: `FILE "C:\Music\" WAVE`, want: []string{"FILE", `C:\Music\`, "WAVE"}},
+		{name: "windows path", line: `FILE "C:\Music\01.wav" WAVE`, want: []string{"FILE", `C:\Music\01.wav`, "WAVE"}},
+		{name: "backslash before doubled quote", line: `X "a\""b"`, want: []string{"X", `a\"b`}},
+		{name: "text after closing quote", line: `FILE "Track 01".wav WAVE`, want: []string{"FILE", "Track 01.wav", "WAVE"}},
+		{name: "quotes inside argument", line: `FILE a"b c"d WAVE`, want: []string{"FILE", "ab cd", "WAVE"}},
+		{name: "several quoted arguments", line: `X "a b" "c \"d\" e" f`, want: []string{"X", "a b", `c "d" e`, "f"}},
+		{name: "unclosed", line: `TITLE "My Album`, wantErr: true},
+		{name: "unclosed inside argument", line: `TITLE 12" Single`, wantErr: true},
+		{name: "unclosed lenient", line: `TITLE "My Album`, lenient: true, want: []string{"TITLE", "My Album"}},
+		{name: "unclosed UTF-8 lenient", line: `TITLE "Ёж  и заяц`, lenient: true, want: []string{"TITLE", "Ёж  и заяц"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := smartSplit(tc.line, nil, tc.lenient)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("smartSplit(%q) got %q, want an error", tc.line, got)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("smartSplit(%q) got %q, %v; want %q", tc.line, got, err, tc.want)
+			}
+		})
+	}
+}
+
+// TestQuote checks that every quoted value splits back to itself alone and,
+// with doubled quotes, next to other quoted arguments.
+func TestQuote(t *testing.T) {
+	values := []string{
+		"", " ", `"`, `""`, `" "`, `12" Single`, `He said "hi"`, `"quoted"`,
+		`C:\Music\`, `a\"b`, `a\"`, `\`, `\\server\share`, "tab\there", "Зимняя дорога",
+	}
+	for _, v := range values {
+		for _, line := range []string{"TITLE " + quote(v), "X " + quoteDoubled(v) + " " + quoteDoubled(v) + " WAVE"} {
+			got, err := smartSplit(line, nil, false)
+			if err != nil {
+				t.Errorf("smartSplit(%q) returned an unexpected error: %v", line, err)
+				continue
+			}
+			want := []string{"TITLE", v}
+			if strings.HasPrefix(line, "X ") {
+				want = []string{"X", v, v, "WAVE"}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("smartSplit(%q) got %q, want %q", line, got, want)
+			}
+		}
+	}
+}
+
+// TestParse_QuotedCommand checks that a quoted command name is rejected
+// unless the parser is lenient.
+func TestParse_QuotedCommand(t *testing.T) {
+	if _, err := Parse(strings.NewReader(`"TITLE" "a"`)); err == nil || err.Error() != "line 1: invalid quoting: command cannot be quoted" {
+		t.Errorf("got error %v", err)
+	}
+	sheet, err := (&Parser{Lenient: true}).Parse(strings.NewReader(`"TITLE" "a"`))
+	if err != nil || sheet.Title != "a" {
+		t.Errorf("lenient parse got %v, %v", sheet, err)
+	}
+}
+
+// TestQuote_Raw checks that unambiguous inner quotes are written as is.
+func TestQuote_Raw(t *testing.T) {
+	testCases := []struct {
+		value string
+		want  string
+	}{
+		{`12" Single`, `"12" Single"`},
+		{`He said "hi" twice`, `"He said "hi" twice"`},
+		{`a"b`, `"a"b"`},
+		{`"`, `""""`},
+		{`a\"b`, `"a\""b"`},
+	}
+	for _, tc := range testCases {
+		if got := quote(tc.value); got != tc.want {
+			t.Errorf("quote(%q) got %s, want %s", tc.value, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CDSampleRate — частота дискретизации Audio CD. Один фрейм CUE равен
+// ровно 588 сэмплам на этой частоте.
+const CDSampleRate = 44100
+
+// Samples переводит таймкод в номер сэмпла для указанной частоты дискретизации.
+// Вычисление выполняется в целых числах: для частот, кратных 75 (44100, 48000,
+// 88200, 96000, 192000 и т.д.), результат точный. Для прочих частот позиция
+// округляется вниз до целого сэмпла.
+func (t Timecode) Samples(sampleRate int) int64 {
+	return int64(t.TotalFrames()) * int64(sampleRate) / FramesPerSecond
+}
+
+// TimecodeFromSamples переводит номер сэмпла в таймкод. Если позиция не попадает
+// на границу фрейма, таймкод округляется вниз, а exact будет равен false.
+func TimecodeFromSamples(samples int64, sampleRate int) (tc Timecode, exact bool) {
+	if samples <= 0 || sampleRate <= 0 {
+		return Timecode{}, samples == 0 && sampleRate > 0
+	}
+	scaled := samples * FramesPerSecond
+	return NewTimecodeFromFrames(int(scaled / int64(sampleRate))), scaled%int64(sampleRate) == 0
+}
+
+// Samples переводит позицию индекса в номер сэмпла. Если индекс был задан
+// нестандартным точным таймкодом, используется точная позиция, округленная
+// до ближайшего сэмпла.
+func (i Index) Samples(sampleRate int) int64 {
+	if i.Precise <= 0 {
+		return i.Time.Samples(sampleRate)
+	}
+	// Делим отдельно целые секунды и остаток, чтобы не переполнить int64.
+	rate := int64(sampleRate)
+	secs := int64(i.Precise / time.Second)
+	rem := int64(i.Precise % time.Second)
+	return secs*rate + (rem*rate+int64(time.Second)/2)/int64(time.Second)
+}
+
+// StartSample возвращает номер сэмпла, с которого начинается трек (INDEX 01),
+// относительно начала его файла.
+func (t *Track) StartSample(sampleRate int) int64 {
+	for _, idx := range t.Indices {
+		if idx.Number == 1 {
+			return idx.Samples(sampleRate)
+		}
+	}
+	return 0
+}
+
+// EndSample возвращает номер сэмпла, на котором заканчивается трек (исключительно),
+// то есть начало следующего трека в том же файле. Для последнего трека файла
+// конец неизвестен, и ok будет равен false.
+func (t *Track) EndSample(sampleRate int) (end int64, ok bool) {
+	if t.parentFile == nil {
+		return 0, false
+	}
+	next := t.parentFile.findNextTrack(t)
+	if next == nil {
+		return 0, false
+	}
+	end = next.StartSample(sampleRate)
+	if end < t.StartSample(sampleRate) {
+		return 0, false // Некорректные данные в CUE.
+	}
+	return end, true
+}
+
+// parsePreciseTimecode разбирает нестандартный таймкод с долями секунды
+// в формате "MM:SS.ddd" (до 9 знаков после точки). Такие таймкоды пишут
+// некоторые программы, чтобы задать позицию точнее одного фрейма.
+func parsePreciseTimecode(s string) (time.Duration, error) {
+	minStr, secStr, ok := strings.Cut(s, ":")
+	if !ok || strings.Contains(secStr, ":") {
+		return 0, errors.New("precise timecode must be in MM:SS.ddd format")
+	}
+	minutes, err := strconv.Atoi(minStr)
+	if err != nil || minutes < 0 {
+		return 0, fmt.Errorf("invalid minutes value: %s", minStr)
+	}
+
+	wholeStr, fracStr, _ := strings.Cut(secStr, ".")
+	seconds, err := strconv.Atoi(wholeStr)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid seconds value: %s", secStr)
+	}
+	if seconds > 59 {
+		return 0, fmt.Errorf("seconds value cannot exceed 59: %d", seconds)
+	}
+	if len(fracStr) > 9 {
+		return 0, fmt.Errorf("fraction of a second is too precise: %s", fracStr)
+	}
+	nanos := 0
+	if fracStr != "" {
+		nanos, err = strconv.Atoi(fracStr + strings.Repeat("0", 9-len(fracStr)))
+		if err != nil || nanos < 0 {
+			return 0, fmt.Errorf("invalid fraction of a second: %s", fracStr)
+		}
+	}
+
+	return time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(nanos), nil
+}
@@ -0,0 +1,88 @@
+package gocue
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestTimecode_Samples checks exact frame-to-sample conversion at common rates.
+func TestTimecode_Samples(t *testing.T) {
+	tc := Timecode{Minutes: 6, Seconds: 19, Frames: 35}
+	frames := int64(tc.TotalFrames())
+
+	testCases := []struct {
+		rate int
+		want int64
+	}{
+		{44100, frames * 588},
+		{48000, frames * 640},
+		{96000, frames * 1280},
+		{192000, frames * 2560},
+	}
+	for _, c := range testCases {
+		if got := tc.Samples(c.rate); got != c.want {
+			t.Errorf("Samples(%d) got %d, want %d", c.rate, got, c.want)
+		}
+		back, exact := TimecodeFromSamples(c.want, c.rate)
+		if back != tc || !exact {
+			t.Errorf("TimecodeFromSamples(%d, %d) got %s exact=%v, want %s exact=true", c.want, c.rate, back, exact, tc)
+		}
+	}
+
+	back, exact := TimecodeFromSamples(frames*588+1, 44100)
+	if back != tc || exact {
+		t.Errorf("TimecodeFromSamples() off-boundary got %s exact=%v, want %s exact=false", back, exact, tc)
+	}
+}
+
+// TestTrack_StartEndSample checks track boundaries in samples.
+func TestTrack_StartEndSample(t *testing.T) {
+	cue := `
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 01 00:02:01
+`
+	sheet, err := Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	track1, track2 := sheet.Files[0].Tracks[0], sheet.Files[0].Tracks[1]
+
+	if got := track2.StartSample(48000); got != 151*640 {
+		t.Errorf("track 2 StartSample(48000) got %d, want %d", got, 151*640)
+	}
+	if end, ok := track1.EndSample(44100); !ok || end != 151*588 {
+		t.Errorf("track 1 EndSample(44100) got %d ok=%v, want %d ok=true", end, ok, 151*588)
+	}
+	if _, ok := track2.EndSample(44100); ok {
+		t.Error("last track EndSample() reported a known end")
+	}
+}
+
+// TestParser_LenientPreciseTimecode checks decimal-second INDEX timecodes in lenient mode.
+func TestParser_LenientPreciseTimecode(t *testing.T) {
+	cue := "FILE \"a.wav\" WAVE\nTRACK 01 AUDIO\nINDEX 01 01:02.5000227"
+
+	if _, err := Parse(strings.NewReader(cue)); err == nil {
+		t.Fatal("Parse() accepted a precise timecode in strict mode")
+	}
+
+	sheet, err := (&Parser{Lenient: true}).Parse(strings.NewReader(cue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	idx := sheet.Files[0].Tracks[0].Indices[0]
+	if idx.Precise != 62*time.Second+500022700 {
+		t.Errorf("got Precise %v, want 1m2.5000227s", idx.Precise)
+	}
+	if idx.Time.String() != "01:02:37" {
+		t.Errorf("got Time %s, want 01:02:37", idx.Time)
+	}
+	// 62.5000227 с * 44100 = 2756251.00107 -> ближайший сэмпл 2756251.
+	if got := sheet.Files[0].Tracks[0].StartSample(44100); got != 2756251 {
+		t.Errorf("got StartSample %d, want 2756251", got)
+	}
+}
@@ -0,0 +1,180 @@
+// Package tags переводит метаданные CUE sheet в теги аудиофайлов.
+// Поддерживаются соглашения Vorbis comment (FLAC, Ogg), ID3v2.4 (MP3)
+// и APEv2 (APE, WavPack); названия полей совпадают с теми, что используют
+// foobar2000 и MusicBrainz Picard, поэтому файлы, размеченные разными
+// программами, читаются одинаково.
+package tags
+
+import (
+	"strconv"
+
+	"github.com/theurs/gocue"
+)
+
+// Format — формат тегов.
+type Format int
+
+const (
+	// Vorbis — Vorbis comment: ключи в верхнем регистре, например "ALBUMARTIST".
+	Vorbis Format = iota
+	// ID3v2 — фреймы ID3v2.4, например "TPE2". Нестандартные поля записываются
+	// фреймами TXXX с описанием после двоеточия: "TXXX:CATALOGNUMBER".
+	// Для "COMM" язык и описание комментария выбирает программа записи.
+	ID3v2
+	// APEv2 — ключи APEv2, например "Album Artist".
+	APEv2
+)
+
+// String возвращает название формата.
+func (f Format) String() string {
+	switch f {
+	case Vorbis:
+		return "Vorbis comment"
+	case ID3v2:
+		return "ID3v2.4"
+	case APEv2:
+		return "APEv2"
+	}
+	return "unknown"
+}
+
+// Tag — одно поле тега.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+// field — ключи одного поля в каждом из форматов. Пустой ключ означает,
+// что поле в этом формате не записывается.
+type field struct {
+	vorbis, id3, ape string
+}
+
+// key возвращает ключ поля в формате f.
+func (fl field) key(f Format) string {
+	switch f {
+	case Vorbis:
+		return fl.vorbis
+	case ID3v2:
+		return fl.id3
+	case APEv2:
+		return fl.ape
+	}
+	return ""
+}
+
+// Соответствие полей по таблицам foobar2000 и MusicBrainz Picard.
+var (
+	fieldTitle         = field{"TITLE", "TIT2", "Title"}
+	fieldArtist        = field{"ARTIST", "TPE1", "Artist"}
+	fieldAlbumArtist   = field{"ALBUMARTIST", "TPE2", "Album Artist"}
+	fieldAlbum         = field{"ALBUM", "TALB", "Album"}
+	fieldComposer      = field{"COMPOSER", "TCOM", "Composer"}
+	fieldGenre         = field{"GENRE", "TCON", "Genre"}
+	fieldDate          = field{"DATE", "TDRC", "Year"}
+	fieldTrack         = field{"TRACKNUMBER", "TRCK", "Track"}
+	fieldTrackTotal    = field{"TRACKTOTAL", "", ""}
+	fieldTotalTracks   = field{"TOTALTRACKS", "", ""}
+	fieldDisc          = field{"DISCNUMBER", "TPOS", "Disc"}
+	fieldDiscTotal     = field{"DISCTOTAL", "", ""}
+	fieldTotalDiscs    = field{"TOTALDISCS", "", ""}
+	fieldCompilation   = field{"COMPILATION", "TCMP", "Compilation"}
+	fieldISRC          = field{"ISRC", "TSRC", "ISRC"}
+	fieldBarcode       = field{"BARCODE", "TXXX:BARCODE", "Barcode"}
+	fieldCatalogNumber = field{"CATALOGNUMBER", "TXXX:CATALOGNUMBER", "CatalogNumber"}
+	fieldLabel         = field{"LABEL", "TPUB", "Label"}
+	fieldComment       = field{"COMMENT", "COMM", "Comment"}
+)
+
+// replayGainKeys — REM ReplayGain, которые пишут EAC и foobar2000. Значения
+// альбома берутся из REM диска, значения трека — из REM трека.
+var replayGainKeys = []struct {
+	key   string
+	album bool
+}{
+	{"REPLAYGAIN_ALBUM_GAIN", true},
+	{"REPLAYGAIN_ALBUM_PEAK", true},
+	{"REPLAYGAIN_TRACK_GAIN", false},
+	{"REPLAYGAIN_TRACK_PEAK", false},
+}
+
+// Track возвращает теги трека t из CUE sheet c в формате f. Значения берутся
+// из Track.Effective, поэтому наследуют данные диска. Дополнительно
+// записываются CATALOG диска (как штрихкод), REM CATALOGNUMBER, REM LABEL,
+// значения ReplayGain и отметка о коррекции предыскажений (REM DEEMPHASIS).
+// Пустые поля пропускаются, порядок тегов постоянен.
+//
+// Номера трека и диска в ID3v2 и APEv2 записываются в виде "n/всего",
+// а в Vorbis comment общее количество выносится в отдельные поля
+// TRACKTOTAL/TOTALTRACKS и DISCTOTAL/TOTALDISCS, как это делает Picard.
+func Track(c *gocue.Cuesheet, t *gocue.Track, f Format) []Tag {
+	m := t.Effective()
+	var tags []Tag
+	add := func(fl field, value string) {
+		if key := fl.key(f); key != "" && value != "" {
+			tags = append(tags, Tag{Key: key, Value: value})
+		}
+	}
+
+	add(fieldTitle, m.Title)
+	add(fieldArtist, m.Artist)
+	add(fieldAlbumArtist, m.AlbumArtist)
+	add(fieldAlbum, m.Album)
+	add(fieldComposer, m.Composer)
+	add(fieldGenre, m.Genre)
+	add(fieldDate, m.Date)
+
+	if f == Vorbis {
+		add(fieldTrack, number(m.TrackNumber))
+		add(fieldTrackTotal, number(m.TotalTracks))
+		add(fieldTotalTracks, number(m.TotalTracks))
+		add(fieldDisc, number(m.DiscNumber))
+		add(fieldDiscTotal, number(m.TotalDiscs))
+		add(fieldTotalDiscs, number(m.TotalDiscs))
+	} else {
+		add(fieldTrack, position(m.TrackNumber, m.TotalTracks))
+		add(fieldDisc, position(m.DiscNumber, m.TotalDiscs))
+	}
+	if m.Compilation {
+		add(fieldCompilation, "1")
+	}
+
+	add(fieldISRC, m.ISRC)
+	add(fieldBarcode, c.Catalog)
+	catalogNumber, _ := c.RemValue("CATALOGNUMBER")
+	add(fieldCatalogNumber, catalogNumber)
+	label, _ := c.RemValue("LABEL")
+	add(fieldLabel, label)
+	add(fieldComment, m.Comment)
+
+	for _, rg := range replayGainKeys {
+		rem := t.RemValue
+		if rg.album {
+			rem = c.RemValue
+		}
+		value, _ := rem(rg.key)
+		add(field{rg.key, "TXXX:" + rg.key, rg.key}, value)
+	}
+	deemphasis, _ := t.RemValue(gocue.RemDeemphasis)
+	add(field{gocue.RemDeemphasis, "TXXX:" + gocue.RemDeemphasis, gocue.RemDeemphasis}, deemphasis)
+	return tags
+}
+
+// number форматирует положительное число; ноль означает, что значения нет.
+func number(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// position форматирует номер в виде "n/всего" или "n", если всего неизвестно.
+func position(n, total int) string {
+	if n <= 0 {
+		return ""
+	}
+	if total <= 0 {
+		return strconv.Itoa(n)
+	}
+	return strconv.Itoa(n) + "/" + strconv.Itoa(total)
+}
@@ -0,0 +1,143 @@
+package tags
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/theurs/gocue"
+)
+
+const testCue = `
+REM GENRE Jazz
+REM DATE 1959
+REM DISCNUMBER 1/2
+REM CATALOGNUMBER CL 1355
+REM REPLAYGAIN_ALBUM_GAIN -6.50 dB
+REM REPLAYGAIN_ALBUM_PEAK 0.988525
+CATALOG 0074643135527
+PERFORMER "Miles Davis"
+TITLE "Kind of Blue"
+FILE "CDImage.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    ISRC USSM15900113
+    REM REPLAYGAIN_TRACK_GAIN -7.10 dB
+    REM REPLAYGAIN_TRACK_PEAK 0.912000
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Freddie Freeloader"
+    PERFORMER "Miles Davis & Wynton Kelly"
+    REM DEEMPHASIS 50/15us
+    INDEX 01 09:22:00
+`
+
+// TestTrack checks the per-format key mapping.
+func TestTrack(t *testing.T) {
+	sheet, err := gocue.Parse(strings.NewReader(testCue))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name   string
+		track  int
+		format Format
+		want   []Tag
+	}{
+		{
+			name:   "vorbis",
+			track:  1,
+			format: Vorbis,
+			want: []Tag{
+				{"TITLE", "So What"},
+				{"ARTIST", "Miles Davis"},
+				{"ALBUMARTIST", "Miles Davis"},
+				{"ALBUM", "Kind of Blue"},
+				{"GENRE", "Jazz"},
+				{"DATE", "1959"},
+				{"TRACKNUMBER", "1"},
+				{"TRACKTOTAL", "2"},
+				{"TOTALTRACKS", "2"},
+				{"DISCNUMBER", "1"},
+				{"DISCTOTAL", "2"},
+				{"TOTALDISCS", "2"},
+				{"COMPILATION", "1"},
+				{"ISRC", "USSM15900113"},
+				{"BARCODE", "0074643135527"},
+				{"CATALOGNUMBER", "CL 1355"},
+				{"REPLAYGAIN_ALBUM_GAIN", "-6.50 dB"},
+				{"REPLAYGAIN_ALBUM_PEAK", "0.988525"},
+				{"REPLAYGAIN_TRACK_GAIN", "-7.10 dB"},
+				{"REPLAYGAIN_TRACK_PEAK", "0.912000"},
+			},
+		},
+		{
+			name:   "id3v2",
+			track:  2,
+			format: ID3v2,
+			want: []Tag{
+				{"TIT2", "Freddie Freeloader"},
+				{"TPE1", "Miles Davis & Wynton Kelly"},
+				{"TPE2", "Miles Davis"},
+				{"TALB", "Kind of Blue"},
+				{"TCON", "Jazz"},
+				{"TDRC", "1959"},
+				{"TRCK", "2/2"},
+				{"TPOS", "1/2"},
+				{"TCMP", "1"},
+				{"TXXX:BARCODE", "0074643135527"},
+				{"TXXX:CATALOGNUMBER", "CL 1355"},
+				{"TXXX:REPLAYGAIN_ALBUM_GAIN", "-6.50 dB"},
+				{"TXXX:REPLAYGAIN_ALBUM_PEAK", "0.988525"},
+				{"TXXX:DEEMPHASIS", "50/15us"},
+			},
+		},
+		{
+			name:   "apev2",
+			track:  1,
+			format: APEv2,
+			want: []Tag{
+				{"Title", "So What"},
+				{"Artist", "Miles Davis"},
+				{"Album Artist", "Miles Davis"},
+				{"Album", "Kind of Blue"},
+				{"Genre", "Jazz"},
+				{"Year", "1959"},
+				{"Track", "1/2"},
+				{"Disc", "1/2"},
+				{"Compilation", "1"},
+				{"ISRC", "USSM15900113"},
+				{"Barcode", "0074643135527"},
+				{"CatalogNumber", "CL 1355"},
+				{"REPLAYGAIN_ALBUM_GAIN", "-6.50 dB"},
+				{"REPLAYGAIN_ALBUM_PEAK", "0.988525"},
+				{"REPLAYGAIN_TRACK_GAIN", "-7.10 dB"},
+				{"REPLAYGAIN_TRACK_PEAK", "0.912000"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Track(sheet, sheet.Track(tc.track), tc.format)
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("Track() got\n%v\nwant\n%v", got, tc.want)
+			}
+		})
+	}
+}
+
+// TestTrack_Minimal checks that empty fields are omitted.
+func TestTrack_Minimal(t *testing.T) {
+	sheet := gocue.NewCuesheet()
+	track, err := sheet.AddFile("a.wav", "WAVE").AddTrack(1, "AUDIO")
+	if err != nil {
+		t.Fatalf("AddTrack() returned an unexpected error: %v", err)
+	}
+	got := Track(sheet, track, ID3v2)
+	want := []Tag{{"TRCK", "1/1"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("Track() got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package gocue
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rounding задает способ округления при переводе произвольной длительности в фреймы.
+type Rounding int
+
+const (
+	// RoundFloor округляет вниз, к началу фрейма.
+	RoundFloor Rounding = iota
+	// RoundNearest округляет к ближайшему фрейму (половина округляется вверх).
+	RoundNearest
+	// RoundCeil округляет вверх, к началу следующего фрейма.
+	RoundCeil
+)
+
+// ParseTimecode разбирает строку формата "MM:SS:FF" в структуру Timecode.
+func ParseTimecode(s string) (Timecode, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return Timecode{}, errors.New("timecode must be in MM:SS:FF format")
+	}
+
+	minutes, err := timecodeField(parts[0])
+	if err != nil {
+		return Timecode{}, fmt.Errorf("invalid minutes value: %s", parts[0])
+	}
+
+	seconds, err := timecodeField(parts[1])
+	if err != nil {
+		return Timecode{}, fmt.Errorf("invalid seconds value: %s", parts[1])
+	}
+	if seconds > 59 {
+		return Timecode{}, fmt.Errorf("seconds value cannot exceed 59: %d", seconds)
+	}
+
+	frames, err := timecodeField(parts[2])
+	if err != nil {
+		return Timecode{}, fmt.Errorf("invalid frames value: %s", parts[2])
+	}
+	if frames >= FramesPerSecond {
+		return Timecode{}, fmt.Errorf("frames value must be less than %d: %d", FramesPerSecond, frames)
+	}
+
+	return Timecode{Minutes: minutes, Seconds: seconds, Frames: frames}, nil
+}
+
+// timecodeField разбирает поле таймкода. Знак не допускается, поэтому
+// отрицательные значения отклоняются.
+func timecodeField(s string) (int, error) {
+	if s == "" || s[0] == '-' || s[0] == '+' {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.Atoi(s)
+}
+
+// TimecodeFromDuration переводит time.Duration в Timecode, округляя до целого
+// фрейма указанным способом. Отрицательные длительности дают нулевой таймкод.
+func TimecodeFromDuration(d time.Duration, rounding Rounding) Timecode {
+	if d <= 0 {
+		return Timecode{}
+	}
+	// Работаем в целых числах: фреймы = d * 75 / 1e9. Делим отдельно на целые
+	// секунды и остаток, чтобы не переполнить int64 на больших длительностях.
+	secs := int64(d / time.Second)
+	rem := int64(d % time.Second)
+	frames := secs * FramesPerSecond
+	frac := rem * FramesPerSecond // Числитель остатка со знаменателем 1e9.
+	frames += frac / int64(time.Second)
+	frac %= int64(time.Second)
+
+	switch rounding {
+	case RoundNearest:
+		if frac*2 >= int64(time.Second) {
+			frames++
+		}
+	case RoundCeil:
+		if frac > 0 {
+			frames++
+		}
+	}
+	return NewTimecodeFromFrames(int(frames))
+}
+
+// Add возвращает сумму двух таймкодов.
+func (t Timecode) Add(other Timecode) Timecode {
+	return NewTimecodeFromFrames(t.TotalFrames() + other.TotalFrames())
+}
+
+// Sub возвращает разность t - other. Таймкод не может быть отрицательным,
+// поэтому если other позже t, результатом будет нулевой таймкод.
+func (t Timecode) Sub(other Timecode) Timecode {
+	return NewTimecodeFromFrames(t.TotalFrames() - other.TotalFrames())
+}
+
+// Compare сравнивает два таймкода и возвращает -1, если t раньше other,
+// +1, если позже, и 0, если они совпадают. Подходит для slices.SortFunc.
+func (t Timecode) Compare(other Timecode) int {
+	a, b := t.TotalFrames(), other.TotalFrames()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Before сообщает, находится ли t раньше other.
+func (t Timecode) Before(other Timecode) bool {
+	return t.Compare(other) < 0
+}
+
+// After сообщает, находится ли t позже other.
+func (t Timecode) After(other Timecode) bool {
+	return t.Compare(other) > 0
+}
+
+// MarshalText реализует encoding.TextMarshaler и возвращает таймкод в формате "MM:SS:FF".
+func (t Timecode) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText реализует encoding.TextUnmarshaler для строк формата "MM:SS:FF".
+func (t *Timecode) UnmarshalText(text []byte) error {
+	tc, err := ParseTimecode(string(text))
+	if err != nil {
+		return err
+	}
+	*t = tc
+	return nil
+}
+
+// Set реализует flag.Value, чтобы таймкод можно было передать через флаг
+// командной строки, например --start 03:21:40.
+func (t *Timecode) Set(s string) error {
+	return t.UnmarshalText([]byte(s))
+}
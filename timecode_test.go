@@ -0,0 +1,91 @@
+package gocue
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+// TestTimecode_Arithmetic checks Add, Sub and the comparison helpers.
+func TestTimecode_Arithmetic(t *testing.T) {
+	a := Timecode{Minutes: 3, Seconds: 59, Frames: 70}
+	b := Timecode{Seconds: 1, Frames: 10}
+
+	if got := a.Add(b).String(); got != "04:01:05" {
+		t.Errorf("Add() got %s, want 04:01:05", got)
+	}
+	if got := a.Sub(b).String(); got != "03:58:60" {
+		t.Errorf("Sub() got %s, want 03:58:60", got)
+	}
+	if got := b.Sub(a); got != (Timecode{}) {
+		t.Errorf("Sub() with later operand got %s, want 00:00:00", got)
+	}
+	if a.Compare(b) != 1 || b.Compare(a) != -1 || a.Compare(a) != 0 {
+		t.Error("Compare() returned wrong ordering")
+	}
+	if !b.Before(a) || !a.After(b) || a.Before(a) {
+		t.Error("Before()/After() returned wrong ordering")
+	}
+}
+
+// TestTimecodeFromDuration checks all rounding modes.
+func TestTimecodeFromDuration(t *testing.T) {
+	// 1 фрейм = 13.333... мс, поэтому 20 мс лежат между 1-м и 2-м фреймом.
+	testCases := []struct {
+		d        time.Duration
+		rounding Rounding
+		want     string
+	}{
+		{20 * time.Millisecond, RoundFloor, "00:00:01"},
+		{20 * time.Millisecond, RoundNearest, "00:00:02"},
+		{20 * time.Millisecond, RoundCeil, "00:00:02"},
+		{15 * time.Millisecond, RoundNearest, "00:00:01"},
+		{2 * time.Second, RoundCeil, "00:02:00"},
+		{-time.Second, RoundCeil, "00:00:00"},
+		{Timecode{Minutes: 74, Seconds: 33, Frames: 74}.AsDuration(), RoundNearest, "74:33:74"},
+	}
+
+	for _, tc := range testCases {
+		if got := TimecodeFromDuration(tc.d, tc.rounding).String(); got != tc.want {
+			t.Errorf("TimecodeFromDuration(%v, %d) got %s, want %s", tc.d, tc.rounding, got, tc.want)
+		}
+	}
+}
+
+// TestTimecode_TextAndFlag checks encoding.TextMarshaler and flag.Value support.
+func TestTimecode_TextAndFlag(t *testing.T) {
+	var start Timecode
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&start, "start", "start position")
+	if err := fs.Parse([]string{"--start", "03:21:40"}); err != nil {
+		t.Fatalf("flag parsing returned an unexpected error: %v", err)
+	}
+	if start != (Timecode{Minutes: 3, Seconds: 21, Frames: 40}) {
+		t.Errorf("flag value got %s, want 03:21:40", start)
+	}
+	for _, bad := range []string{"03:21", "-1:00:00", "00:-5:00", "00:00:-1", "+1:00:00"} {
+		if err := fs.Parse([]string{"--start", bad}); err == nil {
+			t.Errorf("flag parsing accepted a malformed timecode %q", bad)
+		}
+		if _, err := ParseTimecode(bad); err == nil {
+			t.Errorf("ParseTimecode(%q) accepted a malformed timecode", bad)
+		}
+	}
+
+	text, err := start.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText() returned an unexpected error: %v", err)
+	}
+	if string(text) != "03:21:40" {
+		t.Errorf("MarshalText() got %s, want 03:21:40", text)
+	}
+	var decoded Timecode
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText() returned an unexpected error: %v", err)
+	}
+	if decoded != start {
+		t.Errorf("round trip got %s, want %s", decoded, start)
+	}
+}
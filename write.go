@@ -0,0 +1,195 @@
+package gocue
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Write записывает CUE sheet c в w в текстовом формате CUE.
+//
+// Команды каждого элемента выводятся в постоянном порядке: сначала REM,
+// затем метаданные и команды CD-TEXT, затем нераспознанные при разборе
+// команды (Extensions) в исходном порядке. Значения записываются по тем же
+// правилам кавычек, что и при разборе, и читаются обратно без изменений.
+// Индексы, лежащие в другом файле (Index.File), записываются в блоке своего
+// файла: для раскладки с паузами в конце предыдущего файла TRACK и INDEX 00
+// попадают в старый FILE, а INDEX 01 — в новый. Позиции с Index.Precise
+// записываются обычным таймкодом Index.Time. Строки завершаются переводом
+// строки c.LineEnding.
+func Write(w io.Writer, c *Cuesheet) error {
+	cw := &cueWriter{w: w, eol: cmp.Or(c.LineEnding, LineEndingLF)}
+	for _, rem := range c.Rem {
+		cw.rem(0, rem)
+	}
+	cw.bare(0, "CATALOG", c.Catalog)
+	cw.text(0, "CDTEXTFILE", c.CDTextFile)
+	cw.text(0, "PERFORMER", c.Performer)
+	cw.text(0, "SONGWRITER", c.Songwriter)
+	cw.text(0, "COMPOSER", c.Composer)
+	cw.text(0, "ARRANGER", c.Arranger)
+	cw.text(0, "MESSAGE", c.Message)
+	cw.text(0, "GENRE", c.Genre)
+	cw.bare(0, "DISC_ID", c.DiscID)
+	cw.bare(0, "UPC_EAN", c.UPCEAN)
+	cw.bare(0, "TOC_INFO", c.TOCInfo)
+	cw.bare(0, "TOC_INFO2", c.TOCInfo2)
+	cw.bare(0, "SIZE_INFO", c.SizeInfo)
+	cw.text(0, "TITLE", c.Title)
+	cw.commands(0, c.Extensions)
+
+	// spanning — трек, чей TRACK уже записан в предыдущем FILE.
+	var spanning *Track
+	for i, f := range c.Files {
+		cw.line(0, "FILE", quote(f.Name), f.Type)
+		cw.commands(2, f.Extensions)
+		if spanning != nil {
+			cw.indices(spanning, f, f)
+			cw.timecode(4, "POSTGAP", spanning.Postgap)
+		}
+		for _, t := range f.Tracks {
+			if t == spanning {
+				continue
+			}
+			cw.track(t)
+			cw.indices(t, f, f)
+			cw.timecode(4, "POSTGAP", t.Postgap)
+		}
+
+		// Первый трек следующего файла, начало которого лежит в этом файле.
+		spanning = nil
+		if i+1 < len(c.Files) && len(c.Files[i+1].Tracks) > 0 {
+			next := c.Files[i+1].Tracks[0]
+			if len(next.Indices) > 0 && next.Indices[0].File == f {
+				cw.track(next)
+				cw.indices(next, c.Files[i+1], f)
+				spanning = next
+			}
+		}
+	}
+	return cw.err
+}
+
+// cueWriter построчно записывает CUE sheet и запоминает первую ошибку записи.
+type cueWriter struct {
+	w   io.Writer
+	eol LineEnding
+	err error
+}
+
+// line записывает команду с аргументами и отступом в indent пробелов.
+func (cw *cueWriter) line(indent int, command string, args ...string) {
+	if cw.err != nil {
+		return
+	}
+	_, cw.err = fmt.Fprintf(cw.w, "%s%s%s", strings.Repeat(" ", indent), strings.Join(append([]string{command}, args...), " "), string(cw.eol))
+}
+
+// text записывает команду с текстовым аргументом в кавычках, если он задан.
+func (cw *cueWriter) text(indent int, command, value string) {
+	if value != "" {
+		cw.line(indent, command, quote(value))
+	}
+}
+
+// bare записывает команду с аргументом, если он задан. Кавычки ставятся,
+// только если без них значение не прочитать обратно.
+func (cw *cueWriter) bare(indent int, command, value string) {
+	if value != "" {
+		cw.line(indent, command, quoteIfNeeded(value))
+	}
+}
+
+// rem записывает комментарий. Текст REM хранится без кавычек и склеен
+// через пробел. Поле вида KEY value с многословным значением записывается
+// как REM KEY "value", как это делает EAC; свободный текст записывается
+// как есть, а в кавычки целиком заключается, только если без них он не
+// прочитается обратно (повторяющиеся пробелы, табуляция, кавычки).
+func (cw *cueWriter) rem(indent int, text string) {
+	key, value, ok := strings.Cut(text, " ")
+	switch {
+	case !ok:
+		cw.line(indent, "REM", quoteIfNeeded(key))
+	case isRemKey(key) && (strings.Contains(value, " ") || !plainRem(value)):
+		cw.line(indent, "REM", key, quote(value))
+	case plainRem(text):
+		cw.line(indent, "REM", text)
+	default:
+		cw.line(indent, "REM", quote(text))
+	}
+}
+
+// isRemKey сообщает, похоже ли слово на ключ REM вроде GENRE или
+// REPLAYGAIN_TRACK_GAIN: заглавные латинские буквы, цифры и подчеркивания.
+func isRemKey(s string) bool {
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// plainRem сообщает, прочитается ли текст без кавычек обратно без изменений.
+func plainRem(s string) bool {
+	return s != "" && s[0] != ' ' && s[len(s)-1] != ' ' &&
+		!strings.Contains(s, "  ") && !strings.ContainsAny(s, "\t\"")
+}
+
+// timecode записывает команду с таймкодом, если он не нулевой.
+func (cw *cueWriter) timecode(indent int, command string, t Timecode) {
+	if t.TotalFrames() != 0 {
+		cw.line(indent, command, t.String())
+	}
+}
+
+// commands записывает нераспознанные команды.
+func (cw *cueWriter) commands(indent int, cmds []Command) {
+	for _, cmd := range cmds {
+		args := make([]string, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			args[i] = quoteIfNeeded(arg)
+		}
+		// quote проверяет значение в одиночку; если кавычки нескольких
+		// аргументов мешают друг другу, все они удваиваются.
+		if got, err := smartSplit(strings.Join(args, " "), nil, false); err != nil || !slices.Equal(got, cmd.Args) {
+			for i, arg := range cmd.Args {
+				if needsQuotes(arg) {
+					args[i] = quoteDoubled(arg)
+				}
+			}
+		}
+		cw.line(indent, cmd.Name, args...)
+	}
+}
+
+// track записывает заголовок трека и его метаданные вплоть до PREGAP.
+func (cw *cueWriter) track(t *Track) {
+	cw.line(2, "TRACK", fmt.Sprintf("%02d", t.Number), t.Type)
+	cw.text(4, "TITLE", t.Title)
+	cw.text(4, "PERFORMER", t.Performer)
+	cw.text(4, "SONGWRITER", t.Songwriter)
+	cw.text(4, "COMPOSER", t.Composer)
+	cw.text(4, "ARRANGER", t.Arranger)
+	cw.text(4, "MESSAGE", t.Message)
+	for _, rem := range t.Rem {
+		cw.rem(4, rem)
+	}
+	if len(t.Flags) > 0 {
+		cw.line(4, "FLAGS", t.Flags...)
+	}
+	cw.bare(4, "ISRC", t.ISRC)
+	cw.commands(4, t.Extensions)
+	cw.timecode(4, "PREGAP", t.Pregap)
+}
+
+// indices записывает индексы трека t из файла owner, лежащие в файле f.
+func (cw *cueWriter) indices(t *Track, owner, f *File) {
+	for _, idx := range t.Indices {
+		if cmp.Or(idx.File, owner) == f {
+			cw.line(4, "INDEX", fmt.Sprintf("%02d", idx.Number), idx.Time.String())
+		}
+	}
+}
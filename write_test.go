@@ -0,0 +1,152 @@
+package gocue
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestWrite checks the exact output for sheets that are already in the
+// writer's canonical form.
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name string
+		cue  string
+	}{
+		{
+			name: "full sheet",
+			cue: `REM GENRE Jazz
+REM DATE 1959
+REM This is a plain comment
+CATALOG 0074643135527
+PERFORMER "Miles Davis"
+COMPOSER "Bill Evans"
+DISC_ID 3A0A5E05
+UPC_EAN 0074643135527
+TITLE "Kind of Blue"
+X_VENDOR "two words" 3
+FILE "Kind of Blue.wav" WAVE
+  X_FILE
+  TRACK 01 AUDIO
+    TITLE "So What"
+    COMPOSER "Miles Davis"
+    REM REPLAYGAIN_TRACK_GAIN "-3.10 dB"
+    FLAGS DCP PRE
+    ISRC USSM15900113
+    X_TRACK yes
+    INDEX 00 00:00:00
+    INDEX 01 00:00:32
+  TRACK 02 AUDIO
+    TITLE "Freddie Freeloader"
+    PREGAP 00:02:00
+    INDEX 01 09:22:00
+    POSTGAP 00:01:00
+`,
+		},
+		{
+			name: "gaps appended to the previous file",
+			cue: `FILE "01.wav" WAVE
+  TRACK 01 AUDIO
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Two"
+    INDEX 00 04:10:20
+FILE "02.wav" WAVE
+    INDEX 01 00:00:00
+  TRACK 03 AUDIO
+    INDEX 00 03:00:00
+    INDEX 01 03:02:00
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sheet, err := Parse(strings.NewReader(tc.cue))
+			if err != nil {
+				t.Fatalf("Parse() returned an unexpected error: %v", err)
+			}
+			var b strings.Builder
+			if err := Write(&b, sheet); err != nil {
+				t.Fatalf("Write() returned an unexpected error: %v", err)
+			}
+			if b.String() != tc.cue {
+				t.Errorf("Write() got:\n%s\nwant:\n%s", b.String(), tc.cue)
+			}
+		})
+	}
+}
+
+// TestWrite_RoundTrip checks that a written sheet parses back to the same model.
+func TestWrite_RoundTrip(t *testing.T) {
+	sheet, err := Parse(strings.NewReader(`
+REM COMMENT "ExactAudioCopy v1.6"
+REM COMMENT "a  b"
+REM GENRE "Rock Music"
+REM This is a plain comment
+REM "free  text with ""quotes"""
+TITLE "Album"
+MESSAGE "Hello"
+GENRE "Hip Hop"
+FILE "a.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "One"
+    ARRANGER "Someone"
+    REM NOTE "two  spaces"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    INDEX 00 03:00:00
+FILE "b.wav" WAVE
+    INDEX 01 00:00:00
+    INDEX 02 01:00:00
+    POSTGAP 00:00:10
+`))
+	if err != nil {
+		t.Fatalf("Parse() returned an unexpected error: %v", err)
+	}
+	var b strings.Builder
+	if err := Write(&b, sheet); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	got, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Parse() of written sheet returned an unexpected error: %v\n%s", err, b.String())
+	}
+	if !reflect.DeepEqual(got, sheet) {
+		t.Errorf("round trip changed the sheet:\n%s", b.String())
+	}
+}
+
+// TestWrite_Quoting checks that values with quotes and backslashes survive
+// a write and a second parse.
+func TestWrite_Quoting(t *testing.T) {
+	sheet := NewCuesheet()
+	sheet.LineEnding = LineEndingCRLF
+	sheet.Title = `He said "hi"`
+	sheet.Performer = `12" Single`
+	sheet.Rem = []string{`COMMENT "quoted" text`}
+	sheet.Extensions = []Command{
+		{Name: "X_PATH", Args: []string{`C:\Music\`, "", `a"b`}},
+		{Name: "X_NAME", Args: []string{`12" Single`, "WAVE"}},
+	}
+	file := sheet.AddFile(`C:\Music\"Live".wav`, "WAVE")
+	track, _ := file.AddTrack(1, "AUDIO")
+	track.Title = `a\"b`
+	track.Indices = []Index{{Number: 1}}
+
+	var b strings.Builder
+	if err := Write(&b, sheet); err != nil {
+		t.Fatalf("Write() returned an unexpected error: %v", err)
+	}
+	for _, want := range []string{`PERFORMER "12" Single"`, `X_NAME "12" Single" WAVE`} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("written sheet does not contain %s:\n%s", want, b.String())
+		}
+	}
+	got, err := Parse(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("Parse() of written sheet returned an unexpected error: %v\n%s", err, b.String())
+	}
+	if !reflect.DeepEqual(got, sheet) {
+		t.Errorf("round trip changed the sheet:\n%s", b.String())
+	}
+}